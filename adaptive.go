@@ -0,0 +1,81 @@
+package apifast
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// AdaptiveSettings tunes the adaptive concurrency limit of a host, which
+// grows additively while attempts succeed and shrinks multiplicatively (AIMD)
+// when they fail or slow down
+type AdaptiveSettings struct {
+	InitialLimit     int           // Attempts in flight allowed at first, 20 if zero
+	MinLimit         int           // Lower bound of the limit, 1 if zero
+	MaxLimit         int           // Upper bound of the limit, 200 if zero
+	LatencyThreshold time.Duration // Attempts slower than this count as congestion; only failures do if zero
+	Backoff          float64       // Factor applied to the limit on congestion, 0.9 if zero
+}
+
+// AdaptiveConcurrency limits the attempts in flight to each host with a limit
+// that adapts to the host's health. Every successful attempt raises the limit
+// by a fraction, about one per round trip, while network errors, 5xx responses
+// and attempts slower than LatencyThreshold cut it by Backoff, at most once
+// per round trip. Attempts over the limit queue like with MaxConcurrent.
+func (c *Client) AdaptiveConcurrency(settings AdaptiveSettings) *Client {
+	if settings.MinLimit <= 0 {
+		settings.MinLimit = 1
+	}
+	if settings.MaxLimit <= 0 {
+		settings.MaxLimit = 200
+	}
+	if settings.InitialLimit <= 0 {
+		settings.InitialLimit = 20
+	}
+	settings.InitialLimit = min(max(settings.InitialLimit, settings.MinLimit), settings.MaxLimit)
+	if settings.Backoff <= 0 || settings.Backoff >= 1 {
+		settings.Backoff = 0.9
+	}
+	c.ensureBulkhead().adaptive = &settings
+	return c
+}
+
+// adaptiveLimit is the AIMD-controlled concurrency limit of a host
+type adaptiveLimit struct {
+	settings *AdaptiveSettings
+	sem      *semaphore
+
+	mu      sync.Mutex
+	limit   float64
+	lastCut time.Time
+}
+
+func newAdaptiveLimit(settings *AdaptiveSettings) *adaptiveLimit {
+	return &adaptiveLimit{
+		settings: settings,
+		sem:      newSemaphore(settings.InitialLimit),
+		limit:    float64(settings.InitialLimit),
+	}
+}
+
+// record adjusts the limit to the outcome of an attempt that took elapsed
+func (l *adaptiveLimit) record(failed bool, elapsed time.Duration) {
+	s := l.settings
+	congested := failed || (s.LatencyThreshold > 0 && elapsed > s.LatencyThreshold)
+
+	l.mu.Lock()
+	now := time.Now()
+	switch {
+	case !congested:
+		l.limit += 1 / l.limit
+	case now.Sub(l.lastCut) >= elapsed:
+		// Attempts of the same round trip see the same congestion
+		l.limit *= s.Backoff
+		l.lastCut = now
+	}
+	l.limit = math.Min(math.Max(l.limit, float64(s.MinLimit)), float64(s.MaxLimit))
+	limit := int(l.limit)
+	l.mu.Unlock()
+
+	l.sem.setLimit(limit)
+}
@@ -3,8 +3,13 @@ package apifast
 import (
 	"context"
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -16,49 +21,106 @@ type Header struct {
 }
 
 type Auth struct {
-	Username string
-	Password string
-	Token    string
+	Username    string
+	Password    string
+	Token       string
+	TokenSource TokenSource // Supplies bearer tokens dynamically, e.g. *ClientCredentials
+	Digest      bool        // Use HTTP Digest instead of Basic authentication for Username/Password
+	APIKey      APIKey      // Static API key sent in a header or query parameter
 }
 
 // RequestOptions represents optional parameters for making API requests
 type RequestOptions struct {
-	Timeout time.Duration // Request timeout duration
-	payload []byte
-	Headers []Header
-	Auth    Auth
+	Timeout     time.Duration // Request timeout duration, covering all retry attempts
+	Retry       RetryPolicy   // Retry behaviour for failed attempts
+	Hedge       HedgePolicy   // Duplicate slow attempts to cut tail latency
+	payload     []byte
+	contentType string
+	multipart   *MultipartForm
+	bodyReader  io.Reader
+	bodySize    int
+	bodyOffset  int64    // start of a seekable bodyReader, for rewinding on retry
+	compression Encoding // content coding applied to the payload
+	Headers     []Header
+	Auth        Auth
 }
 
 type FastBuilder struct {
-	method  string
-	url     string
-	options RequestOptions
-	result  interface{}
+	client         *Client
+	ctx            context.Context
+	method         string
+	url            string
+	options        RequestOptions
+	result         interface{}
+	errorResult    interface{}
+	decoder        func(data []byte, v interface{}) error // overrides content type based decoding
+	envelope       func(body []byte) error                // unwraps protocol envelopes such as GraphQL
+	strictJSON     bool                                   // reject unknown fields when decoding the result
+	endpoint       string                                 // endpoint label of metrics
+	host           string                                 // Host header overriding the URI host
+	sni            string                                 // TLS server name overriding the client's
+	requestID      string                                 // ID sent in the client's request ID header
+	idempotencyKey string                                 // key sent in the Idempotency-Key header
+	memoTTL        time.Duration                          // lifetime of the memoized result
+	priority       int                                    // rank in the queues of the client's limits
+	segments       int                                    // concurrent ranges fetched by Download
+	attempts       int32                                  // attempts sent by the current call, updated atomically
+	checksum       []byte                                 // expected SHA-256 digest of the body
+	payloadFile    string                                 // file streamed as the payload
+	dryRun         bool                                   // build the request without sending it
+	rendering      bool                                   // prepare for AsCurl, without side effects such as token fetches
+	ownAuth        bool                                   // use the request's auth even if empty, never the client default
+	useNumber      bool                                   // decode result numbers as json.Number
+	err            error                                  // deferred error from a builder method
+
+	writer       io.Writer // streaming target for successful response bodies
+	downloadPath string    // file receiving successful response bodies
 }
 
 type Response struct {
-	Code int    // HTTP code
-	Msg  string // Status message
-	Body interface{}
+	Code    int         // HTTP code
+	Msg     string      // Status text, such as "Not Found"
+	Headers http.Header // Response headers
+	Body    []byte      // Response body, owned by the Response; nil when streamed to a writer or file
+
+	Duration time.Duration    // Time the call took, including retries and backoff
+	Attempts int              // Attempts sent, including retries, hedges and fallbacks
+	Timing   *Timing          // Duration breakdown of the attempt, set with Timings
+	Request  *PreparedRequest // The request that would have been sent, set by DryRun
+
+	codec JSONCodec // decodes the body in JSON
 }
 
-// Build initializes a new FastBuilder instance
+// Build initializes a new FastBuilder instance using the shared default client
 func Build() *FastBuilder {
-	return &FastBuilder{}
+	return defaultClient.Build()
 }
 
-// Uri sets the request URL
+// Uri sets the request URL, resolved against the client's base URL when relative
 func (b *FastBuilder) Uri(url string) *FastBuilder {
 	b.url = url
 	return b
 }
 
-// Timeout sets the request timeout
+// WithContext sets the context used to cancel the request and propagate deadlines
+func (b *FastBuilder) WithContext(ctx context.Context) *FastBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// Timeout sets the request timeout covering all attempts, the same as TotalTimeout
 func (b *FastBuilder) Timeout(timeout time.Duration) *FastBuilder {
 	b.options.Timeout = timeout
 	return b
 }
 
+// TotalTimeout sets the hard deadline of the whole call, including every
+// retry attempt and the backoff between them
+func (b *FastBuilder) TotalTimeout(timeout time.Duration) *FastBuilder {
+	b.options.Timeout = timeout
+	return b
+}
+
 // Auth sets the authentication options
 func (b *FastBuilder) Auth(auth Auth) *FastBuilder {
 	b.options.Auth = auth
@@ -77,12 +139,54 @@ func (b *FastBuilder) Payload(payload []byte) *FastBuilder {
 	return b
 }
 
+// PayloadReader streams the request payload from r. size is the body length,
+// or -1 to send it chunked. Retries are only possible when r is an io.Seeker.
+// The reader is not closed by the request.
+func (b *FastBuilder) PayloadReader(r io.Reader, size int) *FastBuilder {
+	b.options.bodyReader = r
+	b.options.bodySize = size
+	if seeker, ok := r.(io.Seeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			b.err = fmt.Errorf("failed to seek payload reader: %w", err)
+			return b
+		}
+		b.options.bodyOffset = offset
+	}
+	return b
+}
+
+// PayloadJSON marshals v as the request payload and sets the JSON content type
+func (b *FastBuilder) PayloadJSON(v interface{}) *FastBuilder {
+	payload, err := b.client.json().Marshal(v)
+	if err != nil {
+		b.err = fmt.Errorf("failed to encode JSON payload: %w", err)
+		return b
+	}
+	b.options.payload = payload
+	b.options.contentType = "application/json"
+	return b
+}
+
+// PayloadForm encodes values as an application/x-www-form-urlencoded payload
+func (b *FastBuilder) PayloadForm(values url.Values) *FastBuilder {
+	b.options.payload = []byte(values.Encode())
+	b.options.contentType = "application/x-www-form-urlencoded"
+	return b
+}
+
 // Result specifies where to store the response result
 func (b *FastBuilder) Result(result interface{}) *FastBuilder {
 	b.result = result
 	return b
 }
 
+// ErrorResult specifies where to store the response body when the status is 400 or above
+func (b *FastBuilder) ErrorResult(result interface{}) *FastBuilder {
+	b.errorResult = result
+	return b
+}
+
 // Get initiates a GET request
 func (b *FastBuilder) Get() (*Response, error) {
 	b.method = "GET"
@@ -107,84 +211,447 @@ func (b *FastBuilder) Delete() (*Response, error) {
 	return b.makeRequest()
 }
 
+// Put initiates a PUT request
+func (b *FastBuilder) Put() (*Response, error) {
+	b.method = "PUT"
+	return b.makeRequest()
+}
+
+// Head initiates a HEAD request
+func (b *FastBuilder) Head() (*Response, error) {
+	b.method = "HEAD"
+	return b.makeRequest()
+}
+
+// Options initiates an OPTIONS request
+func (b *FastBuilder) Options() (*Response, error) {
+	b.method = "OPTIONS"
+	return b.makeRequest()
+}
+
+// Method sets an arbitrary HTTP method to be used by Do
+func (b *FastBuilder) Method(method string) *FastBuilder {
+	b.method = strings.ToUpper(method)
+	return b
+}
+
+// Do initiates the request with the method set by Method, defaulting to GET
+func (b *FastBuilder) Do() (*Response, error) {
+	if b.method == "" {
+		b.method = "GET"
+	}
+	return b.makeRequest()
+}
+
 // makeRequest handles sending the request and receiving the response
-func (b *FastBuilder) makeRequest() (*Response, error) {
-	// Create a context with timeout if specified
-	var ctx context.Context
-	var cancel context.CancelFunc
+func (b *FastBuilder) makeRequest() (response *Response, err error) {
+	start := time.Now()
+	atomic.StoreInt32(&b.attempts, 0)
+
+	// Generated IDs are kept across the retries of this call only, later
+	// calls on the builder generate their own
+	requestID, idempotencyKey := b.requestID, b.idempotencyKey
+	defer func() { b.requestID, b.idempotencyKey = requestID, idempotencyKey }()
+	b.assignRequestID()
+	b.assignIdempotencyKey()
+
+	// Log the request lifecycle through the client's logger
+	if b.client.logger != nil {
+		b.log(LogDebug, "request started")
+		defer func() { b.logOutcome(response, err, time.Since(start)) }()
+	}
+
+	// Surface errors deferred by builder methods
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err := b.client.init(); err != nil {
+		return nil, err
+	}
+	if err := b.checkStrictDecoder(); err != nil {
+		return nil, err
+	}
+	if b.options.multipart != nil {
+		if err := b.options.multipart.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if b.payloadFile != "" {
+		closeFile, err := b.openPayloadFile()
+		if err != nil {
+			return nil, err
+		}
+		defer closeFile()
+	}
+
+	// Return a memoized result without sending the request
+	if response, ok := b.memoized(); ok {
+		response.Duration, response.Attempts = time.Since(start), 0
+		return response, nil
+	}
+
+	// Derive the request context, applying the timeout if specified
+	ctx := b.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if b.requestID != "" {
+		ctx = WithRequestID(ctx, b.requestID)
+	}
 	if b.options.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), b.options.Timeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.options.Timeout)
 		defer cancel()
-	} else {
-		ctx = context.Background()
 	}
 
-	// Create a fasthttp client with the given timeout
-	client := &fasthttp.Client{
-		ReadTimeout:  b.options.Timeout,
-		WriteTimeout: b.options.Timeout,
+	// Copy the call to the mirror, then send the request, retrying according
+	// to the retry policy, unless an identical call is already in flight
+	b.shadow(ctx)
+	response, err = b.collapse(ctx)
+	if err != nil {
+		return nil, b.wrapError(err)
+	}
+	response.Duration = time.Since(start)
+	if b.dryRun {
+		return response, nil
 	}
 
+	// Error statuses are reported with the response; their body is mapped to
+	// the error result if provided, a body that does not decode is left as is
+	if response.Code >= 400 {
+		if b.errorResult != nil {
+			_ = b.decode(response, b.errorResult)
+		}
+		return response, &StatusError{Code: response.Code, Body: response.Body, RequestID: b.requestID}
+	}
+
+	// Unwrap protocol envelopes into the result and their errors
+	if b.envelope != nil {
+		return response, b.envelope(response.Body)
+	}
+
+	// Map response body to the result if provided
+	if b.result != nil && !b.streaming() {
+		if err := b.decodeResult(response); err != nil {
+			return nil, err
+		}
+	}
+	b.memoize(response)
+
+	return response, nil
+}
+
+// send performs a single attempt of the request
+func (b *FastBuilder) send(ctx context.Context) (*Response, error) {
+	// Pick the upstream replica serving this attempt
+	ctx, release := b.client.balancer.pick(ctx)
+	defer release()
+
 	// Prepare the request
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
+	// Build the URI, headers, authentication and body
+	auth, decompressBody, err := b.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the client's request hooks
+	hooked, err := b.client.runBeforeRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop before sending when only the built request is wanted
+	if b.dryRun {
+		return &Response{Request: b.prepared(req)}, nil
+	}
+
+	// Revalidate a cached copy of the response, if any
+	cacheKey := b.cacheKey(req)
+	var cached *fasthttp.Response
+	if cacheKey != "" {
+		if cached = b.client.cache.conditional(ctx, cacheKey, req); cached != nil {
+			defer fasthttp.ReleaseResponse(cached)
+		}
+	}
+
+	// Wait until the client's limits admit the attempt
+	done, err := b.client.admit(ctx, string(req.URI().Host()), b.priority)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a fasthttp response
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	resp.StreamBody = b.streaming()
+
+	// Send the request and report the outcome to the client's limits
+	atomic.AddInt32(&b.attempts, 1)
+	start := time.Now()
+	if b.sni != "" {
+		ctx = withServerName(ctx, string(req.URI().Host()), b.sni)
+	}
+	err = b.exchange(ctx, req, resp, auth, hooked)
+	elapsed := time.Since(start)
+	// Timeouts count as failures, only attempts canceled by the caller or a
+	// winning hedge are left out
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		done(abandoned)
+	case err != nil || resp.StatusCode() >= 500:
+		done(failed)
+	default:
+		done(succeeded)
+	}
+	if b.client.metrics != nil {
+		b.client.metrics.observe(ctx, b, string(req.URI().Host()), elapsed, resp.StatusCode(), err)
+	}
+	if err != nil && b.client.debug != nil {
+		b.dump(req, nil, elapsed, err)
+	}
+	if err != nil && b.client.har != nil {
+		b.client.har.record(b, req, nil, start, elapsed, err)
+	}
+	if errors.Is(err, fasthttp.ErrBodyTooLarge) {
+		return nil, &permanentError{ErrBodyTooLarge}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Serve the cached copy if unchanged, otherwise refresh the cache
+	if cacheKey != "" {
+		b.client.cache.update(ctx, cacheKey, cached, resp)
+	}
+
+	// Verify successful bodies against the digests announced by the server,
+	// which cover the body as sent, and the expected one, which covers the content
+	verify := !b.streaming() && resp.StatusCode() >= 200 && resp.StatusCode() < 300
+	if verify {
+		checks, err := b.headerChecksums(resp)
+		if err == nil {
+			err = verifyChecksums(checks, resp.Body())
+		}
+		if err != nil {
+			return nil, &permanentError{err}
+		}
+	}
+
+	// Decode a compressed body so results map from plain data
+	if decompressBody {
+		if err := decompress(resp, b.client.hc.MaxResponseBodySize); err != nil {
+			return nil, &permanentError{err}
+		}
+	}
+	if verify {
+		if err := verifyChecksums(b.expectedChecksum(), resp.Body()); err != nil {
+			return nil, &permanentError{err}
+		}
+	}
+
+	// Dump and record the attempt while the bodies are still available
+	if b.client.debug != nil {
+		b.dump(req, resp, elapsed, nil)
+	}
+	if b.client.har != nil {
+		b.client.har.record(b, req, resp, start, elapsed, nil)
+	}
+
+	// Stream successful bodies to the configured writer, otherwise copy the
+	// body out since it is only valid until the response is released
+	var body []byte
+	if b.streaming() && resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
+		if err := b.writeBody(ctx, resp); err != nil {
+			return nil, &permanentError{err}
+		}
+	} else {
+		body = append([]byte(nil), resp.Body()...)
+	}
+
+	// Drop a rejected dynamic token so the next request fetches a fresh one
+	if resp.StatusCode() == fasthttp.StatusUnauthorized {
+		if invalidator, ok := auth.TokenSource.(interface{ Invalidate() }); ok {
+			invalidator.Invalidate()
+		}
+	}
+
+	response := &Response{
+		Code:    resp.StatusCode(),
+		Msg:     statusText(resp),
+		Headers: responseHeaders(resp),
+		Body:    body,
+		Timing:  b.client.timings.attempt(resp, start, elapsed),
+		codec:   b.client.json(),
+	}
+
+	// Run the client's response hooks
+	if err := b.client.runAfterResponse(ctx, response); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// prepare sets the URI, headers, authentication and body of req, reporting
+// the authentication in effect and whether the response is to be decompressed
+func (b *FastBuilder) prepare(ctx context.Context, req *fasthttp.Request) (Auth, bool, error) {
+	// Set the request URI and method
+	req.SetRequestURI(rebaseURL(ctx, b.client.resolveURL(b.url)))
+	req.Header.SetMethod(b.method)
+
+	// Set the client's default headers
+	for _, h := range b.client.headers {
+		req.Header.Set(h.Tag, fmt.Sprintf("%v", h.Value))
+	}
+
+	// Set the payload content type; custom headers may override it
+	if b.options.contentType != "" {
+		req.Header.SetContentType(b.options.contentType)
+	}
+
 	// Set custom headers if provided
 	for _, h := range b.options.Headers {
 		req.Header.Set(h.Tag, fmt.Sprintf("%v", h.Value))
 	}
 
+	// Send a Host header independent of the URI
+	if b.host != "" {
+		req.Header.SetHost(b.host)
+		req.UseHostHeader = true
+	}
+
+	// Identify the request unless the caller set their own ID
+	if b.requestID != "" && len(req.Header.Peek(b.client.requestIDHeader)) == 0 {
+		req.Header.Set(b.client.requestIDHeader, b.requestID)
+	}
+
+	// Send the same idempotency key with every attempt
+	if b.idempotencyKey != "" && len(req.Header.Peek("Idempotency-Key")) == 0 {
+		req.Header.Set("Idempotency-Key", b.idempotencyKey)
+	}
+
+	// Forward the trace context of the request context
+	b.propagateTrace(ctx, req)
+
+	// Ask for a compressed response unless the caller handles encodings
+	decompressBody := b.acceptCompression(req)
+
 	// Add Basic or Bearer authentication if provided
-	if b.options.Auth.Username != "" && b.options.Auth.Password != "" {
-		authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte(b.options.Auth.Username+":"+b.options.Auth.Password))
+	auth := b.auth()
+	if auth.Username != "" && auth.Password != "" && !auth.Digest {
+		authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
 		req.Header.Set("Authorization", authHeader)
-	} else if b.options.Auth.Token != "" {
-		authHeader := "Bearer " + b.options.Auth.Token
+	} else if auth.Token != "" {
+		authHeader := "Bearer " + auth.Token
 		req.Header.Set("Authorization", authHeader)
+	} else if auth.TokenSource != nil && b.rendering {
+		req.Header.Set("Authorization", "Bearer "+redacted)
+	} else if auth.TokenSource != nil {
+		token, err := auth.TokenSource.Token(ctx)
+		if err != nil {
+			return Auth{}, false, &permanentError{err}
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if auth.APIKey.Key != "" {
+		auth.APIKey.apply(req)
 	}
-
-	// Set the request URI and method
-	req.SetRequestURI(b.url)
-	req.Header.SetMethod(b.method)
 
 	// Set the request body if payload is provided
-	if b.options.payload != nil {
+	if b.options.multipart != nil {
+		if !b.rendering {
+			b.options.multipart.writeTo(req)
+		}
+	} else if b.options.bodyReader != nil {
+		// Hide Close so fasthttp leaves the caller's reader open
+		req.SetBodyStream(struct{ io.Reader }{b.options.bodyReader}, b.options.bodySize)
+	} else if b.options.payload != nil && b.options.compression != "" {
+		req.SetBody(b.options.compression.compress(b.options.payload))
+		req.Header.Set(fasthttp.HeaderContentEncoding, string(b.options.compression))
+	} else if b.options.payload != nil {
 		req.SetBody(b.options.payload)
 	}
+	b.client.expectBody(req)
 
-	// Create a fasthttp response
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseResponse(resp)
+	// Authenticate with a cached Digest challenge, if any
+	if auth.Digest && !b.rendering {
+		b.client.digestAuthorize(req, auth)
+	}
 
-	// Send the request
-	err := client.DoTimeout(req, resp, b.options.Timeout)
+	return auth, decompressBody, nil
+}
+
+// exchange sends req, following redirects and answering authentication
+// challenges; hooked lists the headers added by request hooks
+func (b *FastBuilder) exchange(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, auth Auth, hooked []string) error {
+	if err := b.client.roundTrip(ctx, req, resp); err != nil {
+		return err
+	}
+	crossed, err := b.followRedirects(ctx, req, resp, auth, hooked)
 	if err != nil {
-		// Check if the error is due to a timeout
-		if ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded {
-			return nil, fmt.Errorf("request timed out")
-		}
-		return nil, fmt.Errorf("request failed: %v", err)
+		return err
+	}
+	// Digest credentials are only answered to the original host
+	if auth.Digest && !crossed {
+		return b.digestHandshake(ctx, req, resp, auth)
 	}
+	return nil
+}
 
-	// Get the response body
-	body := resp.Body()
+// auth returns the request's authentication, falling back to the client default
+func (b *FastBuilder) auth() Auth {
+	a := b.options.Auth
+	if !b.ownAuth && a.Username == "" && a.Password == "" && a.Token == "" && a.TokenSource == nil && !a.Digest && a.APIKey.Key == "" {
+		return b.client.auth
+	}
+	return a
+}
 
-	// Map response body to the result if provided
-	if b.result != nil {
-		if err := mapper(body, b.result); err != nil {
-			return nil, err
-		}
+// rewindBody prepares a streamed payload for another attempt, reporting
+// whether the request can be sent again
+func (b *FastBuilder) rewindBody() bool {
+	if b.options.multipart != nil {
+		return b.options.multipart.rewind()
 	}
+	if b.options.bodyReader == nil {
+		return true
+	}
+	seeker, ok := b.options.bodyReader.(io.Seeker)
+	if !ok {
+		return false
+	}
+	_, err := seeker.Seek(b.options.bodyOffset, io.SeekStart)
+	return err == nil
+}
 
-	// Return the response
-	return &Response{
-		Code: resp.StatusCode(),
-		Msg:  resp.String(),
-		Body: body,
-	}, nil
+// responseHeaders copies the fasthttp response headers into an http.Header
+func responseHeaders(resp *fasthttp.Response) http.Header {
+	headers := make(http.Header)
+	resp.Header.VisitAll(func(key, value []byte) {
+		headers.Add(string(key), string(value))
+	})
+	return headers
 }
 
-// mapper function unmarshals the JSON response into the provided destination
-func mapper[T any](source []byte, dest *T) error {
-	return json.Unmarshal(source, dest)
+// statusText returns the reason phrase sent by the server, or the standard
+// text of the status code
+func statusText(resp *fasthttp.Response) string {
+	if msg := resp.Header.StatusMessage(); len(msg) > 0 {
+		return string(msg)
+	}
+	return fasthttp.StatusMessage(resp.StatusCode())
+}
+
+// decode maps a response body into dest, using the decoder set on the builder
+// or the one registered for the response content type
+func (b *FastBuilder) decode(response *Response, dest interface{}) error {
+	body := response.Body
+	if b.decoder != nil {
+		return b.decoder(body, dest)
+	}
+	return b.client.decoderFor(response.Headers.Get("Content-Type"))(body, dest)
 }
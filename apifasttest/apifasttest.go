@@ -0,0 +1,74 @@
+// Package apifasttest provides an in-memory server for testing code that uses
+// apifast. Requests travel the full client path, hooks, retries and decoding
+// included, over in-memory connections instead of real sockets and ports.
+package apifasttest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/eantaru/apifast"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// URL is the base URL of every Server; requests to it reach the handler
+// whatever host they name
+const URL = "http://apifast.test"
+
+// Server is a fasthttp server listening in memory, with a client wired to it
+type Server struct {
+	// URL is the base URL the Client resolves relative URIs against
+	URL string
+	// Client sends its requests to the server
+	Client *apifast.Client
+
+	ln  *fasthttputil.InmemoryListener
+	srv *fasthttp.Server
+
+	mu    sync.Mutex
+	conns []net.Conn // client connections, closed with the server
+}
+
+// NewServer starts a server running handler. Callers should Close it when done.
+// The client dials the server through DialContext, which must not be replaced.
+func NewServer(handler fasthttp.RequestHandler) *Server {
+	s := &Server{
+		URL: URL,
+		ln:  fasthttputil.NewInmemoryListener(),
+		srv: &fasthttp.Server{Handler: handler},
+	}
+	go s.srv.Serve(s.ln) // returns once the listener is closed
+
+	// Only the dialer is replaced, so the client keeps its own transport settings
+	s.Client = apifast.NewClient().BaseURL(s.URL).DialContext(func(context.Context, string, string) (net.Conn, error) {
+		conn, err := s.ln.Dial()
+		if err == nil {
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+		}
+		return conn, err
+	})
+	return s
+}
+
+// NewHTTPServer starts a server running a net/http handler
+func NewHTTPServer(handler http.Handler) *Server {
+	return NewServer(fasthttpadaptor.NewFastHTTPHandler(handler))
+}
+
+// Close stops the server and closes its connections
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	return err
+}
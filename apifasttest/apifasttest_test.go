@@ -0,0 +1,68 @@
+package apifasttest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eantaru/apifast/apifasttest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name   string
+		server func() *apifasttest.Server
+	}{
+		{
+			name: "fasthttp handler",
+			server: func() *apifasttest.Server {
+				return apifasttest.NewServer(func(ctx *fasthttp.RequestCtx) {
+					ctx.Response.Header.Set("X-Path", string(ctx.Path()))
+					ctx.SetBodyString(string(ctx.Method()) + " " + string(ctx.Host()))
+				})
+			},
+		},
+		{
+			name: "net/http handler",
+			server: func() *apifasttest.Server {
+				return apifasttest.NewHTTPServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("X-Path", r.URL.Path)
+					w.Write([]byte(r.Method + " " + r.Host))
+				}))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := tt.server()
+			if srv.URL != apifasttest.URL {
+				t.Errorf("URL = %q, want %q", srv.URL, apifasttest.URL)
+			}
+
+			// Relative URIs resolve against URL, and every host reaches the handler
+			for uri, host := range map[string]string{
+				"/items":                       "apifast.test",
+				apifasttest.URL + "/items":     "apifast.test",
+				"http://api.example.com/items": "api.example.com",
+			} {
+				resp, err := srv.Client.Build().Uri(uri).Post()
+				if err != nil {
+					t.Fatalf("Post(%q) error = %v", uri, err)
+				}
+				if got := resp.Header("X-Path"); resp.Code != 200 || got != "/items" {
+					t.Errorf("Post(%q) = %d for path %q, want 200 for /items", uri, resp.Code, got)
+				}
+				if want := "POST " + host; resp.String() != want {
+					t.Errorf("Post(%q) body = %q, want %q", uri, resp.String(), want)
+				}
+			}
+
+			if err := srv.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if _, err := srv.Client.Build().Uri("/items").Get(); err == nil {
+				t.Error("Get() succeeded after Close()")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package apifast
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// APIKeyLocation selects where an API key is carried in the request
+type APIKeyLocation int
+
+const (
+	APIKeyInHeader APIKeyLocation = iota // Send the key as a request header
+	APIKeyInQuery                        // Send the key as a query parameter
+)
+
+// APIKey authenticates requests with a static key sent in a header or query parameter
+type APIKey struct {
+	Key  string         // The API key
+	In   APIKeyLocation // Carrier of the key, a header by default
+	Name string         // Header or query parameter name, "X-API-Key" or "api_key" if empty
+}
+
+// name returns the header or query parameter carrying the key
+func (k APIKey) name() string {
+	switch {
+	case k.Name != "":
+		return k.Name
+	case k.In == APIKeyInQuery:
+		return "api_key"
+	}
+	return "X-API-Key"
+}
+
+// apply adds the key to req
+func (k APIKey) apply(req *fasthttp.Request) {
+	if k.In == APIKeyInQuery {
+		req.URI().QueryArgs().Set(k.name(), k.Key)
+		return
+	}
+	req.Header.Set(k.name(), k.Key)
+}
+
+// strip removes the key from req
+func (k APIKey) strip(req *fasthttp.Request) {
+	if k.In != APIKeyInQuery {
+		req.Header.Del(k.name())
+		return
+	}
+	args := req.URI().QueryArgs()
+	if string(args.Peek(k.name())) == k.Key {
+		args.Del(k.name())
+	}
+}
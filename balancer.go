@@ -0,0 +1,189 @@
+package apifast
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// BalanceStrategy decides which upstream serves the next attempt
+type BalanceStrategy int
+
+const (
+	RoundRobin         BalanceStrategy = iota // Upstreams take turns
+	WeightedRoundRobin                        // Upstreams take turns in proportion to their Weight
+	LeastPending                              // The upstream with the fewest attempts in flight
+)
+
+// Upstream is a replica of the API that requests are balanced across
+type Upstream struct {
+	URL    string // Base URL of the replica
+	Weight int    // Relative share of attempts under WeightedRoundRobin, 1 if zero
+}
+
+// LoadBalance spreads requests across upstream replicas without an external
+// load balancer. The first upstream becomes the base URL: every attempt whose
+// URL starts with it is sent to the upstream picked by strategy instead, so
+// retries can land on another replica. Groups share the balancer.
+func (c *Client) LoadBalance(strategy BalanceStrategy, upstreams ...Upstream) *Client {
+	b := &balancer{strategy: strategy}
+	b.update(upstreams)
+	if len(b.upstreams) > 0 {
+		b.base = b.upstreams[0].url
+		c.baseURL = b.base
+	}
+	c.balancer = b
+	return c
+}
+
+// balancer tracks the upstreams of a client
+type balancer struct {
+	strategy BalanceStrategy
+	base     string // URL prefix moved onto the picked upstream
+
+	mu        sync.Mutex
+	upstreams []*upstream
+	next      int // next upstream under RoundRobin
+}
+
+// update replaces the upstreams, keeping the state of those that remain
+func (b *balancer) update(upstreams []Upstream) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current := make(map[string]*upstream, len(b.upstreams))
+	for _, u := range b.upstreams {
+		current[u.url] = u
+	}
+
+	b.upstreams = make([]*upstream, 0, len(upstreams))
+	for _, u := range upstreams {
+		url := strings.TrimRight(u.URL, "/")
+		weight := u.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		if existing, ok := current[url]; ok {
+			existing.weight = weight
+			b.upstreams = append(b.upstreams, existing)
+			continue
+		}
+		b.upstreams = append(b.upstreams, &upstream{url: url, weight: weight})
+	}
+}
+
+// snapshot returns the current upstreams
+func (b *balancer) snapshot() []*upstream {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.upstreams)
+}
+
+// upstream is the state of a single replica
+type upstream struct {
+	url     string
+	weight  int
+	current int // smooth weighted round-robin credit
+	pending int // attempts in flight
+
+	evicted      bool // taken out of the rotation by health checks
+	failedProbes int  // consecutive failed health checks
+	passedProbes int  // consecutive passed health checks
+}
+
+// healthy reports whether u is in the rotation
+func (u *upstream) healthy() bool {
+	return !u.evicted
+}
+
+// rebaseKey is the context key of the base URL an attempt is moved onto
+type rebaseKey struct{}
+
+// rebase moves URLs starting with from onto to
+type rebase struct {
+	from, to string
+}
+
+// withRebase returns a context moving the URLs of attempts from one base URL onto another
+func withRebase(ctx context.Context, from, to string) context.Context {
+	return context.WithValue(ctx, rebaseKey{}, rebase{from: from, to: to})
+}
+
+// rebaseURL moves url onto the base URL chosen for the attempt, if any
+func rebaseURL(ctx context.Context, url string) string {
+	r, ok := ctx.Value(rebaseKey{}).(rebase)
+	if !ok || r.from == r.to || !underBase(url, r.from) {
+		return url
+	}
+	return r.to + url[len(r.from):]
+}
+
+// underBase reports whether url lies under the base URL base, rather than on
+// a longer host or port that merely shares its prefix
+func underBase(url, base string) bool {
+	rest, ok := strings.CutPrefix(url, base)
+	return ok && (rest == "" || strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "?"))
+}
+
+// pick chooses the upstream of the next attempt and returns a context
+// carrying it, along with the function to call once the attempt is done.
+// Attempts already moved elsewhere, such as onto a fallback, are left alone.
+func (b *balancer) pick(ctx context.Context) (context.Context, func()) {
+	if b == nil || ctx.Value(rebaseKey{}) != nil {
+		return ctx, func() {}
+	}
+
+	b.mu.Lock()
+	if len(b.upstreams) == 0 {
+		b.mu.Unlock()
+		return ctx, func() {}
+	}
+	candidates := b.rotation()
+	var u *upstream
+	switch b.strategy {
+	case WeightedRoundRobin:
+		// Smooth weighted round-robin, which interleaves heavy and light upstreams
+		total := 0
+		for _, candidate := range candidates {
+			candidate.current += candidate.weight
+			total += candidate.weight
+			if u == nil || candidate.current > u.current {
+				u = candidate
+			}
+		}
+		u.current -= total
+	case LeastPending:
+		for i := range candidates {
+			candidate := candidates[(b.next+i)%len(candidates)]
+			if u == nil || candidate.pending < u.pending {
+				u = candidate
+			}
+		}
+		b.next++
+	default:
+		u = candidates[b.next%len(candidates)]
+		b.next++
+	}
+	u.pending++
+	b.mu.Unlock()
+
+	return withRebase(ctx, b.base, u.url), func() {
+		b.mu.Lock()
+		u.pending--
+		b.mu.Unlock()
+	}
+}
+
+// rotation returns the healthy upstreams, or all of them if none is healthy
+func (b *balancer) rotation() []*upstream {
+	healthy := make([]*upstream, 0, len(b.upstreams))
+	for _, u := range b.upstreams {
+		if u.healthy() {
+			healthy = append(healthy, u)
+		}
+	}
+	if len(healthy) == 0 {
+		return b.upstreams
+	}
+	return healthy
+}
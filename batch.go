@@ -0,0 +1,41 @@
+package apifast
+
+import (
+	"sync"
+)
+
+// BatchResult is the outcome of one request of a batch
+type BatchResult struct {
+	Response *Response
+	Err      error
+}
+
+// Batch sends the builders concurrently, with at most concurrency requests in
+// flight (unbounded if concurrency <= 0), and returns their results in the
+// order the builders were given. Each builder is sent with the method set by
+// Method, GET by default.
+func Batch(concurrency int, builders ...*FastBuilder) []BatchResult {
+	if concurrency <= 0 || concurrency > len(builders) {
+		concurrency = len(builders)
+	}
+
+	results := make([]BatchResult, len(builders))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				resp, err := builders[i].Do()
+				results[i] = BatchResult{Response: resp, Err: err}
+			}
+		}()
+	}
+	for i := range builders {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,11 @@
+package apifast
+
+// MaxResponseBodySize limits buffered response bodies to n bytes. Reading stops
+// once a server sends more, and the request fails with ErrBodyTooLarge without
+// being retried. The limit also applies to the decoded size of compressed
+// bodies. Streamed bodies (ResultWriter, DownloadToFile, SSE) are not
+// limited, since they are never held in memory.
+func (c *Client) MaxResponseBodySize(n int) *Client {
+	c.hc.MaxResponseBodySize = n
+	return c
+}
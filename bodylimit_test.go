@@ -0,0 +1,95 @@
+package apifast_test
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eantaru/apifast"
+	"github.com/eantaru/apifast/apifasttest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestMaxResponseBodySize(t *testing.T) {
+	const limit = 64 << 10
+	small := bytes.Repeat([]byte("a"), limit/2)
+	large := bytes.Repeat([]byte("a"), 4*limit)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantErr  error
+	}{
+		{"plain body within the limit", "", small, nil},
+		{"plain body over the limit", "", large, apifast.ErrBodyTooLarge},
+		{"gzip body within the limit", "gzip", small, nil},
+		{"gzip body decoding over the limit", "gzip", large, apifast.ErrBodyTooLarge},
+		{"deflate body decoding over the limit", "deflate", large, apifast.ErrBodyTooLarge},
+		{"brotli body within the limit", "br", small, nil},
+		{"brotli body decoding over the limit", "br", large, apifast.ErrBodyTooLarge},
+		{"zstd body within the limit", "zstd", small, nil},
+		{"zstd body decoding over the limit", "zstd", large, apifast.ErrBodyTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := encode(tt.encoding, tt.body)
+			if tt.encoding != "" && len(encoded) > limit {
+				t.Fatalf("%s body of %d bytes exceeds the limit before decoding", tt.encoding, len(encoded))
+			}
+			srv := apifasttest.NewServer(func(ctx *fasthttp.RequestCtx) {
+				if tt.encoding != "" {
+					ctx.Response.Header.Set("Content-Encoding", tt.encoding)
+				}
+				ctx.SetBody(encoded)
+			})
+			defer srv.Close()
+			srv.Client.MaxResponseBodySize(limit)
+
+			resp, err := srv.Client.Build().Uri("/data").Retry(2).Get()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if !bytes.Equal(resp.Body, tt.body) {
+				t.Errorf("body has %d bytes, want %d", len(resp.Body), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestMaxResponseBodySizeIsNotRetried(t *testing.T) {
+	var requests atomic.Int32
+	srv := apifasttest.NewServer(func(ctx *fasthttp.RequestCtx) {
+		requests.Add(1)
+		ctx.Response.Header.Set("Content-Encoding", "gzip")
+		ctx.SetBody(fasthttp.AppendGzipBytes(nil, make([]byte, 1<<20)))
+	})
+	defer srv.Close()
+	srv.Client.MaxResponseBodySize(1 << 10)
+
+	if _, err := srv.Client.Build().Uri("/data").Retry(3).Get(); !errors.Is(err, apifast.ErrBodyTooLarge) {
+		t.Fatalf("Get() error = %v, want ErrBodyTooLarge", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+// encode compresses body with a content coding, "" leaving it as is
+func encode(encoding string, body []byte) []byte {
+	switch encoding {
+	case "gzip":
+		return fasthttp.AppendGzipBytes(nil, body)
+	case "deflate":
+		return fasthttp.AppendDeflateBytes(nil, body)
+	case "br":
+		return fasthttp.AppendBrotliBytes(nil, body)
+	case "zstd":
+		return fasthttp.AppendZstdBytes(nil, body)
+	}
+	return body
+}
@@ -0,0 +1,166 @@
+package apifast
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a host's circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Requests flow normally
+	BreakerOpen                         // Requests fail fast with ErrCircuitOpen
+	BreakerHalfOpen                     // A limited number of probe requests test the host
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerSettings configures the per-host circuit breaker. Network errors and
+// 5xx responses count as failures.
+type BreakerSettings struct {
+	FailureThreshold int                                      // Consecutive failures that open the circuit, 5 if zero
+	ResetTimeout     time.Duration                            // How long the circuit stays open before probing, 30s if zero
+	HalfOpenProbes   int                                      // Successful probes needed to close the circuit, 1 if zero
+	OnStateChange    func(host string, from, to BreakerState) // Called on every state transition
+}
+
+// CircuitBreaker enables a circuit breaker per host, so requests to a failing
+// host fail fast with ErrCircuitOpen instead of piling up timeouts
+func (c *Client) CircuitBreaker(settings BreakerSettings) *Client {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = 5
+	}
+	if settings.ResetTimeout <= 0 {
+		settings.ResetTimeout = 30 * time.Second
+	}
+	if settings.HalfOpenProbes <= 0 {
+		settings.HalfOpenProbes = 1
+	}
+	c.breakers = &circuitBreakers{settings: settings}
+	return c
+}
+
+// circuitBreakers holds the breaker of every host seen by a client
+type circuitBreakers struct {
+	settings BreakerSettings
+	hosts    sync.Map // host -> *circuitBreaker
+}
+
+// get returns the breaker of host, creating it on first use
+func (cbs *circuitBreakers) get(host string) *circuitBreaker {
+	cb, _ := cbs.hosts.LoadOrStore(host, &circuitBreaker{host: host, settings: &cbs.settings})
+	return cb.(*circuitBreaker)
+}
+
+// BreakerState returns the current breaker state of host
+func (c *Client) BreakerState(host string) BreakerState {
+	if c.breakers == nil {
+		return BreakerClosed
+	}
+	cb := c.breakers.get(host)
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// circuitBreaker tracks the health of a single host
+type circuitBreaker struct {
+	host     string
+	settings *BreakerSettings
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int // consecutive failures while closed
+	openedAt  time.Time
+	probes    int // probes admitted while half-open
+	successes int // successful probes while half-open
+}
+
+// allow reports whether a request may be sent to the host
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mu.Lock()
+	from := cb.state
+	allowed := true
+	switch cb.state {
+	case BreakerOpen:
+		if now.Sub(cb.openedAt) < cb.settings.ResetTimeout {
+			allowed = false
+			break
+		}
+		cb.state = BreakerHalfOpen
+		cb.probes = 1
+		cb.successes = 0
+	case BreakerHalfOpen:
+		if cb.probes >= cb.settings.HalfOpenProbes {
+			allowed = false
+			break
+		}
+		cb.probes++
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+	return allowed
+}
+
+// record updates the breaker with the outcome of a request
+func (cb *circuitBreaker) record(failed bool, now time.Time) {
+	cb.mu.Lock()
+	from := cb.state
+	switch cb.state {
+	case BreakerClosed:
+		if !failed {
+			cb.failures = 0
+			break
+		}
+		cb.failures++
+		if cb.failures >= cb.settings.FailureThreshold {
+			cb.state = BreakerOpen
+			cb.openedAt = now
+		}
+	case BreakerHalfOpen:
+		if failed {
+			cb.state = BreakerOpen
+			cb.openedAt = now
+			break
+		}
+		cb.successes++
+		if cb.successes >= cb.settings.HalfOpenProbes {
+			cb.state = BreakerClosed
+			cb.failures = 0
+		}
+	}
+	to := cb.state
+	cb.mu.Unlock()
+
+	cb.notify(from, to)
+}
+
+// abandon gives back the probe slot of a request whose outcome tells nothing
+// about the host, leaving the failure count and state alone
+func (cb *circuitBreaker) abandon() {
+	cb.mu.Lock()
+	if cb.state == BreakerHalfOpen && cb.probes > 0 {
+		cb.probes--
+	}
+	cb.mu.Unlock()
+}
+
+// notify reports a state transition to the observer, if any
+func (cb *circuitBreaker) notify(from, to BreakerState) {
+	if from != to && cb.settings.OnStateChange != nil {
+		cb.settings.OnStateChange(cb.host, from, to)
+	}
+}
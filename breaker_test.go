@@ -0,0 +1,221 @@
+package apifast
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStateMachine(t *testing.T) {
+	// step is a request at offset from the start: allowed is whether the
+	// breaker admits it, and an admitted request then fails, succeeds or is
+	// abandoned
+	type step struct {
+		at        time.Duration
+		allowed   bool
+		failed    bool
+		abandoned bool
+		want      BreakerState
+	}
+	tests := []struct {
+		name        string
+		settings    BreakerSettings
+		steps       []step
+		transitions []BreakerState // states reported to OnStateChange
+	}{
+		{
+			name:     "consecutive failures open the circuit",
+			settings: BreakerSettings{FailureThreshold: 3, ResetTimeout: time.Minute},
+			steps: []step{
+				{at: 0, allowed: true, failed: true, want: BreakerClosed},
+				{at: 0, allowed: true, failed: true, want: BreakerClosed},
+				{at: 0, allowed: true, failed: true, want: BreakerOpen},
+				{at: time.Second, allowed: false, want: BreakerOpen},
+			},
+			transitions: []BreakerState{BreakerOpen},
+		},
+		{
+			name:     "a success resets the failure count",
+			settings: BreakerSettings{FailureThreshold: 2, ResetTimeout: time.Minute},
+			steps: []step{
+				{at: 0, allowed: true, failed: true, want: BreakerClosed},
+				{at: 0, allowed: true, failed: false, want: BreakerClosed},
+				{at: 0, allowed: true, failed: true, want: BreakerClosed},
+				{at: 0, allowed: true, failed: true, want: BreakerOpen},
+			},
+			transitions: []BreakerState{BreakerOpen},
+		},
+		{
+			name:     "a successful probe closes the circuit",
+			settings: BreakerSettings{FailureThreshold: 1, ResetTimeout: time.Minute},
+			steps: []step{
+				{at: 0, allowed: true, failed: true, want: BreakerOpen},
+				{at: 59 * time.Second, allowed: false, want: BreakerOpen},
+				{at: time.Minute, allowed: true, failed: false, want: BreakerClosed},
+				{at: time.Minute, allowed: true, failed: false, want: BreakerClosed},
+			},
+			transitions: []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed},
+		},
+		{
+			name:     "a failed probe reopens the circuit",
+			settings: BreakerSettings{FailureThreshold: 1, ResetTimeout: time.Minute},
+			steps: []step{
+				{at: 0, allowed: true, failed: true, want: BreakerOpen},
+				{at: time.Minute, allowed: true, failed: true, want: BreakerOpen},
+				{at: time.Minute + time.Second, allowed: false, want: BreakerOpen},
+				{at: 2*time.Minute + time.Second, allowed: true, failed: false, want: BreakerClosed},
+			},
+			transitions: []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed},
+		},
+		{
+			name:     "half-open admits only the configured probes",
+			settings: BreakerSettings{FailureThreshold: 1, ResetTimeout: time.Minute, HalfOpenProbes: 2},
+			steps: []step{
+				{at: 0, allowed: true, failed: true, want: BreakerOpen},
+				{at: time.Minute, allowed: true, failed: false, want: BreakerHalfOpen},
+				{at: time.Minute, allowed: true, failed: false, want: BreakerClosed},
+			},
+			transitions: []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed},
+		},
+		{
+			name:     "abandoned requests leave the failure count alone",
+			settings: BreakerSettings{FailureThreshold: 3, ResetTimeout: time.Minute},
+			steps: []step{
+				{at: 0, allowed: true, failed: true, want: BreakerClosed},
+				{at: 0, allowed: true, failed: true, want: BreakerClosed},
+				{at: 0, allowed: true, abandoned: true, want: BreakerClosed},
+				{at: 0, allowed: true, failed: true, want: BreakerOpen},
+			},
+			transitions: []BreakerState{BreakerOpen},
+		},
+		{
+			name:     "an abandoned probe frees its slot without closing the circuit",
+			settings: BreakerSettings{FailureThreshold: 1, ResetTimeout: time.Minute},
+			steps: []step{
+				{at: 0, allowed: true, failed: true, want: BreakerOpen},
+				{at: time.Minute, allowed: true, abandoned: true, want: BreakerHalfOpen},
+				{at: time.Minute, allowed: true, failed: false, want: BreakerClosed},
+			},
+			transitions: []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerClosed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var transitions []BreakerState
+			tt.settings.OnStateChange = func(_ string, _, to BreakerState) {
+				transitions = append(transitions, to)
+			}
+			c := NewClient().CircuitBreaker(tt.settings)
+			cb := c.breakers.get("api.example.com")
+			start := time.Now()
+
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				if allowed := cb.allow(now); allowed != s.allowed {
+					t.Fatalf("step %d: allow = %t, want %t", i, allowed, s.allowed)
+				}
+				switch {
+				case s.abandoned:
+					cb.abandon()
+				case s.allowed:
+					cb.record(s.failed, now)
+				}
+				if got := c.BreakerState("api.example.com"); got != s.want {
+					t.Fatalf("step %d: state = %s, want %s", i, got, s.want)
+				}
+			}
+			if !slices.Equal(transitions, tt.transitions) {
+				t.Errorf("OnStateChange reported %v, want %v", transitions, tt.transitions)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpenLimitsConcurrentProbes(t *testing.T) {
+	c := NewClient().CircuitBreaker(BreakerSettings{FailureThreshold: 1, ResetTimeout: time.Minute})
+	cb := c.breakers.get("api.example.com")
+	start := time.Now()
+	cb.allow(start)
+	cb.record(true, start)
+
+	probe := start.Add(time.Minute)
+	if !cb.allow(probe) {
+		t.Fatal("first probe was rejected")
+	}
+	if cb.allow(probe) {
+		t.Error("second probe was admitted while the first is in flight")
+	}
+	if got := c.BreakerState("api.example.com"); got != BreakerHalfOpen {
+		t.Errorf("state = %s, want %s", got, BreakerHalfOpen)
+	}
+}
+
+func TestCircuitBreakerFailsFast(t *testing.T) {
+	mock := NewMockTransport()
+	mock.On("GET", "/items").Reply(503, "")
+	c := NewClient().BaseURL("https://api.example.com").Transport(mock).
+		CircuitBreaker(BreakerSettings{FailureThreshold: 2, ResetTimeout: time.Minute})
+
+	for range 2 {
+		c.Build().Uri("/items").Get()
+	}
+	_, err := c.Build().Uri("/items").Get()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get() error = %v, want ErrCircuitOpen", err)
+	}
+	if got := len(mock.Requests()); got != 2 {
+		t.Errorf("sent %d requests, want 2", got)
+	}
+}
+
+func TestCircuitBreakerIgnoresCanceledAttempts(t *testing.T) {
+	mock := NewMockTransport()
+	mock.On("GET", "/slow").Reply(200, "").Delay(time.Second)
+	mock.On("GET", "/items").Reply(503, "")
+	c := NewClient().BaseURL("https://api.example.com").Transport(mock).
+		CircuitBreaker(BreakerSettings{FailureThreshold: 3, ResetTimeout: time.Minute})
+
+	c.Build().Uri("/items").Get()
+	c.Build().Uri("/items").Get()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := c.Build().WithContext(ctx).Uri("/slow").Get(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled Get() error = %v, want context.Canceled", err)
+	}
+	c.Build().Uri("/items").Get()
+
+	if got := c.BreakerState("api.example.com"); got != BreakerOpen {
+		t.Errorf("state = %s, want %s", got, BreakerOpen)
+	}
+}
+
+func TestAdaptiveLimitIgnoresAbandonedAttempts(t *testing.T) {
+	tests := []struct {
+		name   string
+		result outcome
+		want   int // sign of the change of the limit
+	}{
+		{name: "a success raises the limit", result: succeeded, want: 1},
+		{name: "a failure cuts the limit", result: failed, want: -1},
+		{name: "an abandoned attempt keeps the limit", result: abandoned, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient().AdaptiveConcurrency(AdaptiveSettings{InitialLimit: 10})
+			done, err := c.bulkhead.acquire(context.Background(), "api.example.com", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			done(tt.result)
+
+			limit, _ := c.bulkhead.adaptiveHosts.Load("api.example.com")
+			got := limit.(*adaptiveLimit).limit
+			if change := cmp.Compare(got, 10); change != tt.want {
+				t.Errorf("limit = %v after starting at 10", got)
+			}
+		})
+	}
+}
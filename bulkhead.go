@@ -0,0 +1,151 @@
+package apifast
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bulkhead caps the attempts in flight of a client, in total and per host
+type bulkhead struct {
+	total    *semaphore // nil if unlimited
+	perHost  int        // zero if unlimited
+	failFast bool
+	hosts    sync.Map // host -> *semaphore
+
+	adaptive      *AdaptiveSettings // nil if disabled
+	adaptiveHosts sync.Map          // host -> *adaptiveLimit
+}
+
+// MaxConcurrent caps the attempts in flight across the client and its groups
+// at n, so a slow dependency cannot tie up every goroutine of the caller.
+// Attempts over the limit queue for a slot, bounded by their context and
+// timeout, unless MaxConcurrentFailFast is set.
+func (c *Client) MaxConcurrent(n int) *Client {
+	c.ensureBulkhead().total = newSemaphore(n)
+	return c
+}
+
+// MaxConcurrentPerHost caps the attempts in flight to each host at n
+func (c *Client) MaxConcurrentPerHost(n int) *Client {
+	c.ensureBulkhead().perHost = max(n, 1)
+	return c
+}
+
+// MaxConcurrentFailFast makes attempts over the concurrency limits fail with
+// ErrConcurrencyLimit instead of queueing
+func (c *Client) MaxConcurrentFailFast() *Client {
+	c.ensureBulkhead().failFast = true
+	return c
+}
+
+// ensureBulkhead returns the client's bulkhead, creating it if needed
+func (c *Client) ensureBulkhead() *bulkhead {
+	if c.bulkhead == nil {
+		c.bulkhead = &bulkhead{}
+	}
+	return c.bulkhead
+}
+
+// acquire takes a slot of the client and of host, queueing by priority, and
+// returns the function that gives them back with the outcome of the attempt
+func (bh *bulkhead) acquire(ctx context.Context, host string, priority int) (func(outcome), error) {
+	var sems []*semaphore
+	if bh.total != nil {
+		sems = append(sems, bh.total)
+	}
+	if bh.perHost > 0 {
+		sem, _ := bh.hosts.LoadOrStore(host, newSemaphore(bh.perHost))
+		sems = append(sems, sem.(*semaphore))
+	}
+	var adaptive *adaptiveLimit
+	if bh.adaptive != nil {
+		limit, _ := bh.adaptiveHosts.LoadOrStore(host, newAdaptiveLimit(bh.adaptive))
+		adaptive = limit.(*adaptiveLimit)
+		sems = append(sems, adaptive.sem)
+	}
+
+	for i, sem := range sems {
+		if err := sem.acquire(ctx, priority, bh.failFast); err != nil {
+			for _, held := range sems[:i] {
+				held.release()
+			}
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	return func(result outcome) {
+		if adaptive != nil && result != abandoned {
+			adaptive.record(result == failed, time.Since(start))
+		}
+		for _, sem := range sems {
+			sem.release()
+		}
+	}, nil
+}
+
+// semaphore admits up to limit holders, queueing the others by priority
+type semaphore struct {
+	mu       sync.Mutex
+	limit    int
+	inFlight int
+	waiters  waitQueue
+}
+
+func newSemaphore(limit int) *semaphore {
+	return &semaphore{limit: max(limit, 1)}
+}
+
+// acquire takes a slot, waiting for one until ctx is done unless failFast is set
+func (s *semaphore) acquire(ctx context.Context, priority int, failFast bool) error {
+	s.mu.Lock()
+	if s.inFlight < s.limit && len(s.waiters) == 0 {
+		s.inFlight++
+		s.mu.Unlock()
+		return nil
+	}
+	if failFast {
+		s.mu.Unlock()
+		return &permanentError{ErrConcurrencyLimit}
+	}
+	w := newWaiter(priority)
+	s.waiters.push(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		queued := s.waiters.remove(w)
+		s.mu.Unlock()
+		// The slot was handed over meanwhile, pass it on
+		if !queued {
+			s.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// release gives a slot back, handing it to the first waiter if any
+func (s *semaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.waiters) > 0 && s.inFlight <= s.limit {
+		s.waiters.pop().signal()
+		return
+	}
+	s.inFlight--
+}
+
+// setLimit changes the number of holders admitted, waking waiters that fit
+func (s *semaphore) setLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = max(limit, 1)
+	for len(s.waiters) > 0 && s.inFlight < s.limit {
+		s.inFlight++
+		s.waiters.pop().signal()
+	}
+}
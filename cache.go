@@ -0,0 +1,148 @@
+package apifast
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CacheStore is the storage behind the response cache. Implementations backed
+// by Redis or bigcache let several instances share cached responses. Errors are
+// treated as cache misses, so a failing store never fails a request.
+type CacheStore interface {
+	// Get returns the value stored under key, if present and not expired
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value under key; a zero ttl keeps it until deleted
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the value stored under key
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryCache is the in-memory CacheStore used by ResponseCache
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time // zero for entries without a ttl
+}
+
+// NewMemoryCache creates an empty in-memory cache store
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry)}
+}
+
+func (m *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (m *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	m.mu.Lock()
+	m.entries[key] = entry
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MemoryCache) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// ResponseCache enables an in-memory cache of GET responses carrying an ETag
+// or Last-Modified validator. Later requests for the same URL are sent as
+// conditional requests, and the cached response is served when the server
+// answers 304 Not Modified.
+func (c *Client) ResponseCache() *Client {
+	return c.ResponseCacheStore(NewMemoryCache(), 0)
+}
+
+// ResponseCacheStore enables the response cache on top of store, keeping
+// entries for ttl, or until replaced if ttl is zero
+func (c *Client) ResponseCacheStore(store CacheStore, ttl time.Duration) *Client {
+	c.cache = &responseCache{store: store, ttl: ttl}
+	return c
+}
+
+// responseCache stores revalidatable responses by URL, shared with groups
+type responseCache struct {
+	store CacheStore
+	ttl   time.Duration
+}
+
+// cacheKey returns the cache key of req, or "" if the request is not cacheable
+func (b *FastBuilder) cacheKey(req *fasthttp.Request) string {
+	if b.client.cache == nil || b.method != fasthttp.MethodGet || b.streaming() {
+		return ""
+	}
+	return req.URI().String()
+}
+
+// conditional returns the cached response for key, if any, and adds its
+// validators to req unless the caller set their own
+func (rc *responseCache) conditional(ctx context.Context, key string, req *fasthttp.Request) *fasthttp.Response {
+	raw, ok, err := rc.store.Get(ctx, key)
+	if err != nil || !ok {
+		return nil
+	}
+
+	cached := fasthttp.AcquireResponse()
+	if err := cached.Read(bufio.NewReader(bytes.NewReader(raw))); err != nil {
+		fasthttp.ReleaseResponse(cached)
+		return nil
+	}
+	if etag := cached.Header.Peek(fasthttp.HeaderETag); len(etag) > 0 && len(req.Header.Peek(fasthttp.HeaderIfNoneMatch)) == 0 {
+		req.Header.SetBytesV(fasthttp.HeaderIfNoneMatch, etag)
+	}
+	if modified := cached.Header.Peek(fasthttp.HeaderLastModified); len(modified) > 0 && len(req.Header.Peek(fasthttp.HeaderIfModifiedSince)) == 0 {
+		req.Header.SetBytesV(fasthttp.HeaderIfModifiedSince, modified)
+	}
+	return cached
+}
+
+// update serves the cached response in place of a 304 Not Modified, stores
+// fresh responses that carry a validator and drops entries that lost theirs
+func (rc *responseCache) update(ctx context.Context, key string, cached, resp *fasthttp.Response) {
+	switch {
+	case resp.StatusCode() == fasthttp.StatusNotModified && cached != nil:
+		cached.CopyTo(resp)
+	case resp.StatusCode() == fasthttp.StatusOK && cacheable(resp):
+		var raw bytes.Buffer
+		if _, err := resp.WriteTo(&raw); err != nil {
+			return
+		}
+		_ = rc.store.Set(ctx, key, raw.Bytes(), rc.ttl)
+	case resp.StatusCode() == fasthttp.StatusOK && cached != nil:
+		_ = rc.store.Delete(ctx, key)
+	}
+}
+
+// cacheable reports whether resp has a validator and may be stored
+func cacheable(resp *fasthttp.Response) bool {
+	if strings.Contains(strings.ToLower(string(resp.Header.Peek(fasthttp.HeaderCacheControl))), "no-store") {
+		return false
+	}
+	return len(resp.Header.Peek(fasthttp.HeaderETag)) > 0 || len(resp.Header.Peek(fasthttp.HeaderLastModified)) > 0
+}
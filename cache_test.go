@@ -0,0 +1,157 @@
+package apifast_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestResponseCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string // headers of the first response
+		request  []apifast.Header  // headers set on the second request
+		second   int               // status of the second response, with body "v2"
+		wantCond [2]string         // conditional header sent with the second request, none if empty
+		wantBody string            // body of the second response
+	}{
+		{
+			name:     "ETag is revalidated",
+			headers:  map[string]string{"ETag": `"v1"`},
+			second:   304,
+			wantCond: [2]string{"If-None-Match", `"v1"`},
+			wantBody: "v1",
+		},
+		{
+			name:     "Last-Modified is revalidated",
+			headers:  map[string]string{"Last-Modified": "Wed, 21 Oct 2026 07:28:00 GMT"},
+			second:   304,
+			wantCond: [2]string{"If-Modified-Since", "Wed, 21 Oct 2026 07:28:00 GMT"},
+			wantBody: "v1",
+		},
+		{
+			name:     "a changed resource replaces the cached one",
+			headers:  map[string]string{"ETag": `"v1"`},
+			second:   200,
+			wantCond: [2]string{"If-None-Match", `"v1"`},
+			wantBody: "v2",
+		},
+		{
+			name:     "the caller's validator takes precedence",
+			headers:  map[string]string{"ETag": `"v1"`},
+			request:  []apifast.Header{{Tag: "If-None-Match", Value: `"v0"`}},
+			second:   200,
+			wantCond: [2]string{"If-None-Match", `"v0"`},
+			wantBody: "v2",
+		},
+		{
+			name:     "no-store is not cached",
+			headers:  map[string]string{"ETag": `"v1"`, "Cache-Control": "no-store"},
+			second:   200,
+			wantBody: "v2",
+		},
+		{
+			name:     "responses without a validator are not cached",
+			headers:  map[string]string{"Content-Language": "en"},
+			second:   200,
+			wantBody: "v2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			first := mock.On("GET", "/status").Reply(200, "v1").Times(1)
+			for k, v := range tt.headers {
+				first.Header(k, v)
+			}
+			mock.On("GET", "/status").Reply(tt.second, "v2")
+			client := apifast.NewClient().Transport(mock).ResponseCache()
+
+			if _, err := client.Build().Uri("https://api.example.com/status").Get(); err != nil {
+				t.Fatalf("first Get() error = %v", err)
+			}
+			resp, err := client.Build().Uri("https://api.example.com/status").Headers(tt.request).Get()
+			if err != nil {
+				t.Fatalf("second Get() error = %v", err)
+			}
+			if resp.Code != 200 || string(resp.Body) != tt.wantBody {
+				t.Errorf("second response = %d %q, want 200 %q", resp.Code, resp.Body, tt.wantBody)
+			}
+
+			second := mock.Requests()[1]
+			for _, name := range []string{"If-None-Match", "If-Modified-Since"} {
+				want := ""
+				if name == tt.wantCond[0] {
+					want = tt.wantCond[1]
+				}
+				if got := second.Header.Get(name); got != want {
+					t.Errorf("second request sent %s %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseCacheDropsEntryWithoutValidator(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("GET", "/status").Reply(200, "v1").Header("ETag", `"v1"`).Times(1)
+	mock.On("GET", "/status").Reply(200, "v2").Times(1)
+	mock.On("GET", "/status").Reply(304, "")
+	client := apifast.NewClient().Transport(mock).ResponseCache()
+
+	for i := range 3 {
+		if _, err := client.Build().Uri("https://api.example.com/status").Get(); i < 2 && err != nil {
+			t.Fatalf("request %d: Get() error = %v", i, err)
+		}
+	}
+	if got := mock.Requests()[2].Header.Get("If-None-Match"); got != "" {
+		t.Errorf("third request sent If-None-Match %q after the validator was dropped", got)
+	}
+}
+
+func TestResponseCacheStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		store    apifast.CacheStore
+		ttl      time.Duration
+		wait     time.Duration // pause between the two requests
+		wantCond bool          // whether the second request is conditional
+	}{
+		{"entry within its ttl", apifast.NewMemoryCache(), time.Minute, 0, true},
+		{"expired entry", apifast.NewMemoryCache(), 10 * time.Millisecond, 20 * time.Millisecond, false},
+		{"failing store is a miss", failingStore{}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On("GET", "/status").Reply(200, "v1").Header("ETag", `"v1"`)
+			client := apifast.NewClient().Transport(mock).ResponseCacheStore(tt.store, tt.ttl)
+
+			for i := range 2 {
+				if _, err := client.Build().Uri("https://api.example.com/status").Get(); err != nil {
+					t.Fatalf("request %d: Get() error = %v", i, err)
+				}
+				time.Sleep(tt.wait)
+			}
+			if got := mock.Requests()[1].Header.Get("If-None-Match") != ""; got != tt.wantCond {
+				t.Errorf("second request conditional = %t, want %t", got, tt.wantCond)
+			}
+		})
+	}
+}
+
+// failingStore is a CacheStore whose every call fails
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("store unavailable")
+}
+
+func (failingStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("store unavailable")
+}
+
+func (failingStore) Delete(context.Context, string) error { return errors.New("store unavailable") }
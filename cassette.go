@@ -0,0 +1,197 @@
+package apifast
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrCassetteMiss is returned in replay mode for requests the cassette has no
+// unused recording of
+var ErrCassetteMiss = errors.New("no recorded interaction matches the request")
+
+// CassetteMode selects whether a Cassette records or replays traffic
+type CassetteMode int
+
+const (
+	CassetteReplay CassetteMode = iota // Serve recorded responses, never touch the network
+	CassetteRecord                     // Send requests and record the responses
+	CassetteAuto                       // Replay if the cassette file exists, record otherwise
+)
+
+// Cassette is a Transport that records real responses to a file and replays
+// them later, making integration tests deterministic and offline-capable.
+// Requests are matched on method, URL and body; each recording is replayed once,
+// in order, so repeated requests get the responses recorded for them.
+type Cassette struct {
+	path string
+	mode CassetteMode
+	next Transport
+
+	mu           sync.Mutex
+	interactions []cassetteInteraction
+	used         []bool
+}
+
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+type cassetteRequest struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Body   cassetteBody `json:"body,omitempty"`
+}
+
+type cassetteResponse struct {
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers,omitempty"`
+	Body    cassetteBody        `json:"body,omitempty"`
+}
+
+// cassetteBody is stored as text when it is valid UTF-8, base64 otherwise
+type cassetteBody []byte
+
+func (b cassetteBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *cassetteBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = []byte(text)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	*b = decoded
+	return err
+}
+
+// NewCassette opens the cassette at path. In record mode requests are sent
+// through next, a new fasthttp.Client if nil, and every interaction is written
+// to path as it completes.
+func NewCassette(path string, mode CassetteMode, next Transport) (*Cassette, error) {
+	if mode == CassetteAuto {
+		mode = CassetteRecord
+		if _, err := os.Stat(path); err == nil {
+			mode = CassetteReplay
+		}
+	}
+	if next == nil {
+		next = &fasthttp.Client{}
+	}
+	c := &Cassette{path: path, mode: mode, next: next}
+
+	if mode == CassetteReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &c.interactions); err != nil {
+			return nil, fmt.Errorf("failed to decode cassette: %w", err)
+		}
+		c.used = make([]bool, len(c.interactions))
+	}
+	return c, nil
+}
+
+// Mode returns the mode the cassette operates in, resolving CassetteAuto
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Do implements Transport
+func (c *Cassette) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	if c.mode == CassetteReplay {
+		return c.replay(req, resp)
+	}
+	return c.record(req, resp, c.next.Do(req, resp))
+}
+
+// DoDeadline implements Transport
+func (c *Cassette) DoDeadline(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	if c.mode == CassetteReplay {
+		return c.replay(req, resp)
+	}
+	return c.record(req, resp, c.next.DoDeadline(req, resp, deadline))
+}
+
+// replay serves the first unused recording matching req
+func (c *Cassette) replay(req *fasthttp.Request, resp *fasthttp.Response) error {
+	method, url := string(req.Header.Method()), req.URI().String()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, in := range c.interactions {
+		if c.used[i] || in.Request.Method != method || in.Request.URL != url || !bytes.Equal(in.Request.Body, req.Body()) {
+			continue
+		}
+		c.used[i] = true
+		resp.Reset()
+		resp.SetStatusCode(in.Response.Status)
+		for key, values := range in.Response.Headers {
+			for _, value := range values {
+				resp.Header.Add(key, value)
+			}
+		}
+		resp.SetBody(in.Response.Body)
+		return nil
+	}
+	return fmt.Errorf("%w: %s %s", ErrCassetteMiss, method, url)
+}
+
+// record appends a completed interaction and rewrites the cassette file
+func (c *Cassette) record(req *fasthttp.Request, resp *fasthttp.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	in := cassetteInteraction{
+		Request: cassetteRequest{
+			Method: string(req.Header.Method()),
+			URL:    req.URI().String(),
+			Body:   append(cassetteBody(nil), req.Body()...),
+		},
+		Response: cassetteResponse{
+			Status:  resp.StatusCode(),
+			Headers: map[string][]string{},
+			Body:    append(cassetteBody(nil), resp.Body()...),
+		},
+	}
+	resp.Header.VisitAll(func(key, value []byte) {
+		in.Response.Headers[string(key)] = append(in.Response.Headers[string(key)], string(value))
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, in)
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
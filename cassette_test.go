@@ -0,0 +1,91 @@
+package apifast_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestCassette(t *testing.T) {
+	binary := []byte{0xff, 0x00, 0xfe}
+	mock := apifast.NewMockTransport()
+	mock.On("GET", "/items").Reply(200, "first").Header("X-Page", "1").Times(1)
+	mock.On("GET", "/items").Reply(200, "second").Header("X-Page", "2")
+	mock.On("POST", "/items").Reply(201, "created")
+	mock.On("GET", "/logo").Reply(200, string(binary))
+
+	path := filepath.Join(t.TempDir(), "fixtures", "items.json")
+	recorder, err := apifast.NewCassette(path, apifast.CassetteAuto, mock)
+	if err != nil {
+		t.Fatalf("NewCassette() error = %v", err)
+	}
+	if recorder.Mode() != apifast.CassetteRecord {
+		t.Fatalf("Mode() = %v without a cassette file, want CassetteRecord", recorder.Mode())
+	}
+	client := apifast.NewClient().BaseURL("https://api.example.com").Transport(recorder)
+	for _, call := range []struct{ method, uri, body string }{
+		{"GET", "/items", ""},
+		{"GET", "/items", ""},
+		{"POST", "/items", `{"name":"a"}`},
+		{"GET", "/logo", ""},
+	} {
+		if _, err := client.Build().Uri(call.uri).Payload([]byte(call.body)).Method(call.method).Do(); err != nil {
+			t.Fatalf("recording %s %s: error = %v", call.method, call.uri, err)
+		}
+	}
+
+	player, err := apifast.NewCassette(path, apifast.CassetteAuto, nil)
+	if err != nil {
+		t.Fatalf("NewCassette() error = %v", err)
+	}
+	if player.Mode() != apifast.CassetteReplay {
+		t.Fatalf("Mode() = %v with a cassette file, want CassetteReplay", player.Mode())
+	}
+	client = apifast.NewClient().BaseURL("https://api.example.com").Transport(player)
+
+	// Replayed in order, so each step consumes one recording
+	tests := []struct {
+		name       string
+		method     string
+		uri        string
+		body       string
+		wantCode   int
+		wantBody   []byte
+		wantHeader string // X-Page of the response
+		wantMiss   bool
+	}{
+		{name: "POST with another body", method: "POST", uri: "/items", body: `{"name":"b"}`, wantMiss: true},
+		{name: "first GET", method: "GET", uri: "/items", wantCode: 200, wantBody: []byte("first"), wantHeader: "1"},
+		{name: "repeated GET", method: "GET", uri: "/items", wantCode: 200, wantBody: []byte("second"), wantHeader: "2"},
+		{name: "GET used up", method: "GET", uri: "/items", wantMiss: true},
+		{name: "POST with the recorded body", method: "POST", uri: "/items", body: `{"name":"a"}`, wantCode: 201, wantBody: []byte("created")},
+		{name: "binary body", method: "GET", uri: "/logo", wantCode: 200, wantBody: binary},
+		{name: "unrecorded URL", method: "GET", uri: "/other", wantMiss: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Build().Uri(tt.uri).Payload([]byte(tt.body)).Method(tt.method).Do()
+			if tt.wantMiss {
+				if !errors.Is(err, apifast.ErrCassetteMiss) {
+					t.Errorf("error = %v, want ErrCassetteMiss", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if resp.Code != tt.wantCode || !bytes.Equal(resp.Body, tt.wantBody) {
+				t.Errorf("response = %d %q, want %d %q", resp.Code, resp.Body, tt.wantCode, tt.wantBody)
+			}
+			if got := resp.Header("X-Page"); got != tt.wantHeader {
+				t.Errorf("X-Page = %q, want %q", got, tt.wantHeader)
+			}
+		})
+	}
+	if n := len(mock.Requests()); n != 4 {
+		t.Errorf("the network saw %d requests, want the 4 recorded ones", n)
+	}
+}
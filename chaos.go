@@ -0,0 +1,83 @@
+package apifast
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrChaosDrop is the error of connections dropped by fault injection
+var ErrChaosDrop = errors.New("chaos: connection dropped")
+
+// ChaosConfig sets the faults injected into a client's requests. Rates are
+// probabilities between 0 and 1, drawn independently for every attempt.
+type ChaosConfig struct {
+	Latency     time.Duration // Delay added before an attempt is sent
+	LatencyRate float64       // Probability of adding the latency
+	DropRate    float64       // Probability of failing with ErrChaosDrop instead of sending
+	ErrorRate   float64       // Probability of answering with ErrorStatus instead of sending
+	ErrorStatus int           // Status of injected error responses, 503 if zero
+	Seed        int64         // Seeds the faults for reproducible runs, random if zero
+}
+
+// Chaos injects latency, dropped connections and error statuses into the
+// client's requests, so retry and circuit breaker behavior can be verified
+// under failure. It wraps the transport in place when called, so set a custom
+// Transport first. Meant for tests and staging only.
+func (c *Client) Chaos(cfg ChaosConfig) *Client {
+	if cfg.ErrorStatus == 0 {
+		cfg.ErrorStatus = fasthttp.StatusServiceUnavailable
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	c.transport = &chaosTransport{next: c.rt(), cfg: cfg, rnd: rand.New(rand.NewSource(seed))}
+	return c
+}
+
+// chaosTransport injects faults before handing attempts to the next transport
+type chaosTransport struct {
+	next Transport
+	cfg  ChaosConfig
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func (t *chaosTransport) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return t.DoDeadline(req, resp, time.Time{})
+}
+
+func (t *chaosTransport) DoDeadline(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	// Draw all faults at once so the sequence only depends on the seed
+	t.mu.Lock()
+	delay := t.rnd.Float64() < t.cfg.LatencyRate
+	drop := t.rnd.Float64() < t.cfg.DropRate
+	fail := t.rnd.Float64() < t.cfg.ErrorRate
+	t.mu.Unlock()
+
+	if delay {
+		if !deadline.IsZero() && time.Until(deadline) < t.cfg.Latency {
+			time.Sleep(time.Until(deadline))
+			return fasthttp.ErrTimeout
+		}
+		time.Sleep(t.cfg.Latency)
+	}
+	switch {
+	case drop:
+		return ErrChaosDrop
+	case fail:
+		resp.Reset()
+		resp.SetStatusCode(t.cfg.ErrorStatus)
+		resp.SetBodyString(fasthttp.StatusMessage(t.cfg.ErrorStatus))
+		return nil
+	}
+	if deadline.IsZero() {
+		return t.next.Do(req, resp)
+	}
+	return t.next.DoDeadline(req, resp, deadline)
+}
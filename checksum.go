@@ -0,0 +1,142 @@
+package apifast
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ExpectChecksum verifies that the body of a successful response, or the file
+// saved by DownloadToFile or Download, has the SHA-256 digest sha256hex. A
+// mismatch fails the request with ErrChecksumMismatch before the result is
+// decoded, and a downloaded file is removed.
+func (b *FastBuilder) ExpectChecksum(sha256hex string) *FastBuilder {
+	want, err := hex.DecodeString(strings.TrimSpace(sha256hex))
+	if err != nil || len(want) != sha256.Size {
+		b.err = fmt.Errorf("invalid SHA-256 checksum %q", sha256hex)
+		return b
+	}
+	b.checksum = want
+	return b
+}
+
+// VerifyChecksums verifies the bodies of successful responses against the
+// Content-MD5 and x-amz-checksum-* headers sent by the server, failing
+// mismatching requests with ErrChecksumMismatch
+func (c *Client) VerifyChecksums() *Client {
+	c.verifyChecksums = true
+	return c
+}
+
+// checksum is a digest a response body must match
+type checksum struct {
+	name string // algorithm, for error messages
+	h    hash.Hash
+	want []byte
+}
+
+// checksumHeaders maps the headers carrying base64 digests to their algorithms
+var checksumHeaders = []struct {
+	header   string
+	name     string
+	newHash  func() hash.Hash
+	fullBody bool // digest of the whole object, even in a range response
+}{
+	{"Content-MD5", "MD5", md5.New, false},
+	{"X-Amz-Checksum-Sha256", "SHA-256", sha256.New, true},
+	{"X-Amz-Checksum-Sha1", "SHA-1", sha1.New, true},
+	{"X-Amz-Checksum-Crc32", "CRC32", func() hash.Hash { return crc32.NewIEEE() }, true},
+	{"X-Amz-Checksum-Crc32c", "CRC32C", func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) }, true},
+}
+
+// headerChecksums returns the digests announced by the headers of resp, if
+// the client verifies them
+func (b *FastBuilder) headerChecksums(resp *fasthttp.Response) ([]*checksum, error) {
+	if !b.client.verifyChecksums {
+		return nil, nil
+	}
+	var checks []*checksum
+	for _, ch := range checksumHeaders {
+		value := resp.Header.Peek(ch.header)
+		if len(value) == 0 || (ch.fullBody && resp.StatusCode() == fasthttp.StatusPartialContent) {
+			continue
+		}
+		want, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(value)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %s header", ErrChecksumMismatch, ch.header)
+		}
+		checks = append(checks, &checksum{name: ch.name, h: ch.newHash(), want: want})
+	}
+	return checks, nil
+}
+
+// expectedChecksum returns the digest set by ExpectChecksum, if any
+func (b *FastBuilder) expectedChecksum() []*checksum {
+	if b.checksum == nil {
+		return nil
+	}
+	return []*checksum{{name: "SHA-256", h: sha256.New(), want: b.checksum}}
+}
+
+// verifyChecksums compares the digests of body with checks
+func verifyChecksums(checks []*checksum, body []byte) error {
+	for _, c := range checks {
+		c.h.Write(body)
+	}
+	return checkDigests(checks)
+}
+
+// checkDigests reports the first digest that does not match
+func checkDigests(checks []*checksum) error {
+	for _, c := range checks {
+		if got := c.h.Sum(nil); !bytes.Equal(got, c.want) {
+			return fmt.Errorf("%w: %s is %x, expected %x", ErrChecksumMismatch, c.name, got, c.want)
+		}
+	}
+	return nil
+}
+
+// checksumWriter hashes a streamed body on its way to w
+type checksumWriter struct {
+	w      io.Writer
+	checks []*checksum
+}
+
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	for _, c := range cw.checks {
+		c.h.Write(p[:n])
+	}
+	return n, err
+}
+
+// verifyFile compares the digest of the file at path with checks
+func verifyFile(path string, checks []*checksum) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read download file: %w", err)
+	}
+	defer f.Close()
+	writers := make([]io.Writer, len(checks))
+	for i, c := range checks {
+		writers[i] = c.h
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return fmt.Errorf("failed to read download file: %w", err)
+	}
+	return checkDigests(checks)
+}
@@ -0,0 +1,342 @@
+package apifast
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpproxy"
+)
+
+// Client owns a long-lived fasthttp.Client from which FastBuilder instances
+// are created, so repeated requests reuse pooled keep-alive connections.
+// A Client is safe for concurrent use once configured; its settings
+// should not be changed while requests are in flight.
+type Client struct {
+	hc            *fasthttp.Client
+	pool          *poolTransport // default transport over hc
+	transport     Transport      // replaces pool if set
+	beforeRequest []RequestHook
+	afterResponse []ResponseHook
+
+	proxy        *fasthttpproxy.Dialer
+	proxyFromEnv bool
+
+	maxRedirects   int
+	redirectPolicy RedirectPolicy
+
+	jar http.CookieJar
+
+	baseURL string
+	headers []Header
+	auth    Auth
+
+	decoders map[string]Decoder // media type -> decoder, copied on write
+	codec    JSONCodec
+
+	disableCompression bool
+
+	pipeline *PipelineSettings // send base URL requests over pipelined connections
+	protocol Protocol
+
+	dialContext         func(ctx context.Context, network, addr string) (net.Conn, error)
+	resolve             map[string][]string // host -> fixed IPs, copied on write
+	dns                 *dnsCache           // shared with groups
+	unixSocket          string
+	localAddr           string
+	localInterface      string
+	ipFamily            IPFamily
+	dialTimeout         time.Duration
+	tlsHandshakeTimeout time.Duration
+
+	debug io.Writer // receives request dumps when debugging
+	har   *HARRecorder
+
+	redactHeaders []string // header values hidden from dumps, beyond the defaults
+	redactFields  []string // body fields and query parameters hidden from dumps
+
+	metrics *Metrics
+	logger  Logger
+
+	requestIDHeader string
+	idempotencyKeys bool // send generated Idempotency-Key headers
+	verifyChecksums bool // verify bodies against Content-MD5 and x-amz-checksum headers
+	b3              bool // also send the b3 trace header
+	noTrace         bool // never send trace headers
+
+	digestChallenges *sync.Map // host -> *digestChallenge, shared with groups
+
+	balancer  *balancer
+	fallback  *fallback
+	mirror    *mirror
+	flights   *flightGroup
+	memo      *memoCache
+	rateLimit *rateLimiter
+	bandwidth *bandwidth
+	expect    *expectContinue
+	timings   *connTimings
+	bulkhead  *bulkhead
+	breakers  *circuitBreakers
+	cache     *responseCache
+
+	healthCheck *HealthCheckSettings
+	discovery   *discovery
+
+	setup *clientSetup // shared with groups derived from the client
+}
+
+// clientSetup tracks the one-time configuration of the underlying fasthttp client
+type clientSetup struct {
+	once sync.Once
+	err  error
+
+	closeOnce sync.Once
+	closed    chan struct{} // closed once the client is closed
+
+	conns connRegistry // open connections, so stalled streams can be aborted
+}
+
+// defaultClient backs the package-level Build function
+var defaultClient = NewClient()
+
+// NewClient initializes a new Client with its own connection pool
+func NewClient() *Client {
+	hc := &fasthttp.Client{}
+	return &Client{
+		hc:               hc,
+		pool:             &poolTransport{hc: hc},
+		setup:            &clientSetup{closed: make(chan struct{})},
+		digestChallenges: &sync.Map{},
+		memo:             &memoCache{entries: make(map[string]memoEntry)},
+	}
+}
+
+// Build initializes a new FastBuilder bound to the client
+func (c *Client) Build() *FastBuilder {
+	return &FastBuilder{client: c}
+}
+
+// BaseURL sets the URL that relative request URIs are resolved against
+func (c *Client) BaseURL(baseURL string) *Client {
+	c.baseURL = baseURL
+	return c
+}
+
+// Headers adds default headers sent with every request; headers set on a
+// FastBuilder override defaults with the same name
+func (c *Client) Headers(headers []Header) *Client {
+	c.headers = append(c.headers, headers...)
+	return c
+}
+
+// Auth sets the default authentication used by requests that do not set their own
+func (c *Client) Auth(auth Auth) *Client {
+	c.auth = auth
+	return c
+}
+
+// Group returns a sub-client whose base URL is extended with pathPrefix.
+// The group shares the connection pool and transport settings of c, while
+// hooks and other settings added to the group only apply to the group.
+func (c *Client) Group(pathPrefix string) *Client {
+	g := *c
+	g.baseURL = joinURL(c.baseURL, pathPrefix)
+	g.beforeRequest = slices.Clip(c.beforeRequest)
+	g.afterResponse = slices.Clip(c.afterResponse)
+	g.headers = slices.Clip(c.headers)
+	g.redactHeaders = slices.Clip(c.redactHeaders)
+	g.redactFields = slices.Clip(c.redactFields)
+	return &g
+}
+
+// resolveURL resolves a request URI against the client's base URL
+func (c *Client) resolveURL(uri string) string {
+	if c.baseURL == "" || strings.Contains(uri, "://") {
+		return uri
+	}
+	return joinURL(c.baseURL, uri)
+}
+
+// joinURL concatenates base and path with exactly one slash between them
+func joinURL(base, path string) string {
+	if path == "" {
+		return base
+	}
+	if base == "" {
+		return path
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// init applies settings that must be in place before the first request
+func (c *Client) init() error {
+	c.setup.once.Do(func() {
+		c.setup.err = c.configure()
+	})
+	return c.setup.err
+}
+
+// configure wires the client settings into the underlying fasthttp client
+func (c *Client) configure() error {
+	if c.proxy != nil {
+		c.proxy.Timeout = c.dialTimeout
+		dial, err := c.proxy.GetDialFunc(c.proxyFromEnv)
+		if err != nil {
+			return fmt.Errorf("invalid proxy configuration: %w", err)
+		}
+		c.hc.Dial = dial
+	}
+	c.configureTimeouts()
+	if err := c.configureProtocol(); err != nil {
+		return err
+	}
+	c.configureExpect()
+	if err := c.pool.configureHost(c.baseURL); err != nil {
+		return err
+	}
+	if c.pipeline != nil {
+		if err := c.pool.configurePipeline(*c.pipeline); err != nil {
+			return err
+		}
+	}
+	c.startDiscovery()
+	c.startHealthChecks()
+	return nil
+}
+
+// Close stops the background work of the client and its groups, such as
+// health checks, and closes idle connections. The client must not be used
+// afterwards.
+func (c *Client) Close() {
+	c.setup.closeOnce.Do(func() { close(c.setup.closed) })
+	c.hc.CloseIdleConnections()
+}
+
+// outcome is the result of an attempt as seen by the client's limits
+type outcome int
+
+const (
+	succeeded outcome = iota
+	failed
+	abandoned // canceled by the caller or a winning hedge, telling nothing about the host
+)
+
+// admit blocks until the client's limits allow an attempt against host. The
+// returned function must be called with the outcome once the attempt is done.
+func (c *Client) admit(ctx context.Context, host string, priority int) (func(outcome), error) {
+	if c.rateLimit != nil {
+		if err := c.rateLimit.wait(ctx, host, priority); err != nil {
+			return nil, err
+		}
+	}
+
+	release := func(outcome) {}
+	if c.bulkhead != nil {
+		var err error
+		if release, err = c.bulkhead.acquire(ctx, host, priority); err != nil {
+			return nil, err
+		}
+	}
+
+	var breaker *circuitBreaker
+	if c.breakers != nil {
+		breaker = c.breakers.get(host)
+		if !breaker.allow(time.Now()) {
+			release(abandoned)
+			return nil, &permanentError{ErrCircuitOpen}
+		}
+	}
+
+	return func(result outcome) {
+		release(result)
+		switch {
+		case breaker == nil:
+		case result == abandoned:
+			breaker.abandon()
+		default:
+			breaker.record(result == failed, time.Now())
+		}
+	}, nil
+}
+
+// roundTrip performs a single exchange, replaying and capturing cookies around the transport
+func (c *Client) roundTrip(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) error {
+	c.applyCookies(req)
+	if err := c.do(ctx, req, resp); err != nil {
+		return err
+	}
+	c.storeCookies(req, resp)
+	return nil
+}
+
+// do sends the request, honouring the deadline and cancellation of ctx
+func (c *Client) do(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) error {
+	deadline, hasDeadline := ctx.Deadline()
+	t := c.rt()
+	if c.transport == nil {
+		t = c.pool.serverNameHost(ctx, req)
+	}
+
+	if ctx.Done() == nil {
+		if hasDeadline {
+			return t.DoDeadline(req, resp, deadline)
+		}
+		return t.Do(req, resp)
+	}
+
+	// fasthttp cannot abort an in-flight call, so it runs on private copies
+	// that are released by whoever finishes last. Streams, such as event
+	// streams waiting for their first event, are canceled the same way.
+	r := fasthttp.AcquireRequest()
+	w := fasthttp.AcquireResponse()
+	req.CopyTo(r)
+	if bodyStream := req.BodyStream(); bodyStream != nil {
+		r.SetBodyStream(bodyStream, req.Header.ContentLength())
+	}
+	w.SkipBody = resp.SkipBody
+	w.StreamBody = resp.StreamBody
+	release := func() {
+		fasthttp.ReleaseRequest(r)
+		fasthttp.ReleaseResponse(w)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		if hasDeadline {
+			done <- t.DoDeadline(r, w, deadline)
+		} else {
+			done <- t.Do(r, w)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		streamBody := resp.StreamBody
+		w.CopyTo(resp)
+		resp.StreamBody = streamBody
+		if stream := w.BodyStream(); stream != nil {
+			// The stream gives its connection back once resp closes it, so
+			// w, which it refers to, is left to the garbage collector
+			resp.SetBodyStream(stream, w.Header.ContentLength())
+			fasthttp.ReleaseRequest(r)
+			return err
+		}
+		release()
+		return err
+	case <-ctx.Done():
+		go func() {
+			<-done
+			// A stream nobody reads must not return its connection to the pool
+			w.Header.SetConnectionClose()
+			release()
+		}()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,31 @@
+package apifast
+
+import "encoding/json"
+
+// JSONCodec encodes and decodes JSON. Implementations such as jsoniter, sonic
+// or go-json can replace encoding/json where decoding is a bottleneck.
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// stdJSON is the encoding/json codec used by default
+type stdJSON struct{}
+
+func (stdJSON) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (stdJSON) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// JSONCodec sets the codec for JSON payloads, results and the JSON based
+// protocols (GraphQL, JSON-RPC, NDJSON streams)
+func (c *Client) JSONCodec(codec JSONCodec) *Client {
+	c.codec = codec
+	return c
+}
+
+// json returns the client's JSON codec
+func (c *Client) json() JSONCodec {
+	if c.codec == nil {
+		return stdJSON{}
+	}
+	return c.codec
+}
@@ -0,0 +1,123 @@
+package apifast
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/valyala/fasthttp"
+)
+
+// Encoding is a content coding for compressing request payloads
+type Encoding string
+
+const (
+	EncodingGzip    Encoding = "gzip"
+	EncodingDeflate Encoding = "deflate"
+	EncodingBrotli  Encoding = "br"
+	EncodingZstd    Encoding = "zstd"
+)
+
+// compress encodes payload with the coding
+func (e Encoding) compress(payload []byte) []byte {
+	switch e {
+	case EncodingGzip:
+		return fasthttp.AppendGzipBytes(nil, payload)
+	case EncodingDeflate:
+		return fasthttp.AppendDeflateBytes(nil, payload)
+	case EncodingBrotli:
+		return fasthttp.AppendBrotliBytes(nil, payload)
+	}
+	return fasthttp.AppendZstdBytes(nil, payload)
+}
+
+// CompressRequest compresses the payload with the given encoding and sets the
+// Content-Encoding header, for APIs that accept compressed uploads. Streamed
+// and multipart bodies are sent as is.
+func (b *FastBuilder) CompressRequest(encoding Encoding) *FastBuilder {
+	switch encoding {
+	case EncodingGzip, EncodingDeflate, EncodingBrotli, EncodingZstd:
+		b.options.compression = encoding
+	default:
+		b.err = fmt.Errorf("unsupported request encoding %q", encoding)
+	}
+	return b
+}
+
+// acceptedEncodings are the content codings decoded transparently
+const acceptedEncodings = "gzip, deflate, br, zstd"
+
+// DisableCompression stops the client from requesting compressed responses
+// and from decompressing response bodies
+func (c *Client) DisableCompression() *Client {
+	c.disableCompression = true
+	return c
+}
+
+// acceptCompression advertises the supported encodings on req, reporting
+// whether the response should be decompressed. Requests that set their own
+// Accept-Encoding handle the response encoding themselves, and streamed
+// responses are requested uncompressed.
+func (b *FastBuilder) acceptCompression(req *fasthttp.Request) bool {
+	if b.client.disableCompression || b.streaming() || b.method == fasthttp.MethodHead {
+		return false
+	}
+	if len(req.Header.Peek(fasthttp.HeaderAcceptEncoding)) > 0 {
+		return false
+	}
+	req.Header.Set(fasthttp.HeaderAcceptEncoding, acceptedEncodings)
+	return true
+}
+
+// decompress replaces a compressed response body with its decoded form,
+// failing with ErrBodyTooLarge once it exceeds maxSize bytes if positive
+func decompress(resp *fasthttp.Response, maxSize int) error {
+	encoding := string(resp.Header.ContentEncoding())
+	if encoding == "" {
+		return nil
+	}
+	r, err := decoder(encoding, bytes.NewReader(resp.Body()))
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s response body: %w", encoding, err)
+	}
+	defer r.Close()
+
+	// Bound the decoded size, not just the compressed one
+	limited := io.Reader(r)
+	if maxSize > 0 {
+		limited = io.LimitReader(r, int64(maxSize)+1)
+	}
+	body, err := io.ReadAll(limited)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s response body: %w", encoding, err)
+	}
+	if maxSize > 0 && len(body) > maxSize {
+		return ErrBodyTooLarge
+	}
+	resp.SetBody(body)
+	resp.Header.Del(fasthttp.HeaderContentEncoding)
+	return nil
+}
+
+// decoder returns a reader decoding the content coding from r
+func decoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case string(EncodingGzip):
+		return gzip.NewReader(r)
+	case string(EncodingDeflate):
+		return zlib.NewReader(r)
+	case string(EncodingBrotli):
+		return io.NopCloser(brotli.NewReader(r)), nil
+	case string(EncodingZstd):
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	}
+	return nil, fasthttp.ErrContentEncodingUnsupported
+}
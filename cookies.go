@@ -0,0 +1,79 @@
+package apifast
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CookieJar stores cookies received in responses and replays them on
+// subsequent requests to matching URLs, following the jar's domain, path
+// and expiry rules. The jar's cookies are added to the Cookie header of a
+// request, replacing cookies of the same name.
+func (c *Client) CookieJar(jar http.CookieJar) *Client {
+	c.jar = jar
+	return c
+}
+
+// Session enables an in-memory cookie jar, so the client behaves like a browser session
+func (c *Client) Session() *Client {
+	jar, _ := cookiejar.New(nil) // only fails on invalid options
+	return c.CookieJar(jar)
+}
+
+// Jar returns the client's cookie jar, nil when cookies are not kept
+func (c *Client) Jar() http.CookieJar {
+	return c.jar
+}
+
+// applyCookies adds the jar's cookies for the URL of req. The cookies of a
+// previous hop are cleared by stripCredentials when a redirect leaves its host.
+func (c *Client) applyCookies(req *fasthttp.Request) {
+	if c.jar == nil {
+		return
+	}
+	u, err := url.Parse(req.URI().String())
+	if err != nil {
+		return
+	}
+	for _, cookie := range c.jar.Cookies(u) {
+		req.Header.SetCookie(cookie.Name, cookie.Value)
+	}
+}
+
+// storeCookies saves the Set-Cookie headers of resp into the jar
+func (c *Client) storeCookies(req *fasthttp.Request, resp *fasthttp.Response) {
+	if c.jar == nil {
+		return
+	}
+	header := make(http.Header)
+	resp.Header.VisitAllCookie(func(_, value []byte) {
+		header.Add("Set-Cookie", string(value))
+	})
+	if len(header) == 0 {
+		return
+	}
+	u, err := url.Parse(req.URI().String())
+	if err != nil {
+		return
+	}
+	c.jar.SetCookies(u, (&http.Response{Header: header}).Cookies())
+}
+
+// Cookies parses the Set-Cookie headers of the response, whether or not the
+// client keeps a cookie jar
+func (r *Response) Cookies() []*http.Cookie {
+	return (&http.Response{Header: r.Headers}).Cookies()
+}
+
+// Cookie returns the cookie name set by the response, nil if none
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package apifast_test
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestCookieJarKeepsRequestCookies(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string   // redirect target of https://a.example/start, none if empty
+		want     []string // cookies of the last request, sorted
+	}{
+		{
+			name: "jar and request cookies are sent together",
+			want: []string{"jar=a", "user=1"},
+		},
+		{
+			name:     "both are kept on a redirect to the same host",
+			location: "/next",
+			want:     []string{"jar=a", "user=1"},
+		},
+		{
+			name:     "only the jar's cookies of another host follow a redirect there",
+			location: "https://b.example/next",
+			want:     []string{"jar=b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			if tt.location != "" {
+				mock.On("GET", "https://a.example/start*").Reply(302, "").Header("Location", tt.location)
+			}
+			mock.On("GET", "*").Reply(200, "ok")
+
+			jar, _ := cookiejar.New(nil)
+			for _, host := range []string{"a", "b"} {
+				u, _ := url.Parse("https://" + host + ".example/")
+				jar.SetCookies(u, []*http.Cookie{{Name: "jar", Value: host}})
+			}
+			client := apifast.NewClient().Transport(mock).FollowRedirects(5).CookieJar(jar)
+
+			_, err := client.Build().
+				Uri("https://a.example/start").
+				Headers([]apifast.Header{{Tag: "Cookie", Value: "user=1"}}).
+				Get()
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			requests := mock.Requests()
+			last := requests[len(requests)-1]
+			var cookies []string
+			for _, c := range strings.Split(last.Header.Get("Cookie"), ";") {
+				if c = strings.TrimSpace(c); c != "" {
+					cookies = append(cookies, c)
+				}
+			}
+			slices.Sort(cookies)
+			if !slices.Equal(cookies, tt.want) {
+				t.Errorf("request to %s sent cookies %q, want %q", last.URL, cookies, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package apifast
+
+import (
+	"context"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// AsCurl renders the request as a copy-pasteable curl command, with the URL,
+// headers, authentication and body it would be sent with. Client request hooks
+// are not run, tokens are not fetched and no request ID or Idempotency-Key is
+// generated. Credentials and redacted headers and fields are replaced with
+// [REDACTED] as in debug dumps.
+func (b *FastBuilder) AsCurl() string {
+	if b.err != nil {
+		return "# " + b.err.Error()
+	}
+	method := b.method
+	if method == "" {
+		method = fasthttp.MethodGet
+	}
+	ctx := b.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	prepared := *b
+	prepared.method = method
+	prepared.rendering = true
+	if b.payloadFile != "" {
+		// The file is opened for its Content-Type, curl reads it itself
+		closeFile, err := prepared.openPayloadFile()
+		if err != nil {
+			return "# failed to build request: " + err.Error()
+		}
+		defer closeFile()
+	}
+	auth, compressed, err := prepared.prepare(ctx, req)
+	if err != nil {
+		return "# failed to build request: " + err.Error()
+	}
+
+	r := prepared.redactor()
+	args := []string{"curl"}
+	switch method {
+	case fasthttp.MethodGet:
+	case fasthttp.MethodHead:
+		args = append(args, "--head")
+	default:
+		args = append(args, "-X", method)
+	}
+	args = append(args, shellQuote(r.redactURL(req.URI())))
+
+	// Headers curl derives itself are left out
+	host := string(req.URI().Host())
+	req.Header.VisitAll(func(key, value []byte) {
+		switch {
+		case string(key) == fasthttp.HeaderContentLength,
+			string(key) == fasthttp.HeaderTransferEncoding,
+			string(key) == fasthttp.HeaderHost && string(value) == host,
+			string(key) == fasthttp.HeaderAcceptEncoding && compressed,
+			string(key) == fasthttp.HeaderContentType && b.options.multipart != nil,
+			string(key) == fasthttp.HeaderAuthorization && auth.Digest:
+			return
+		}
+		args = append(args, "-H", shellQuote(string(key)+": "+r.headerValue(key, value)))
+	})
+	if compressed {
+		args = append(args, "--compressed")
+	}
+	if auth.Digest {
+		args = append(args, "--digest", "--user", shellQuote(auth.Username+":"+redacted))
+	}
+
+	// Render the body
+	switch {
+	case b.options.multipart != nil:
+		for _, p := range b.options.multipart.parts {
+			if p.filename == "" {
+				value := p.value
+				if r.redactsField(p.field) {
+					value = redacted
+				}
+				args = append(args, "--form-string", shellQuote(p.field+"="+value))
+			} else if p.path != "" {
+				args = append(args, "-F", shellQuote(p.field+"=@"+p.path))
+			} else {
+				args = append(args, "-F", shellQuote(p.field+"=@"+p.filename))
+			}
+		}
+	case b.payloadFile != "":
+		args = append(args, "--data-binary", shellQuote("@"+b.payloadFile))
+	case b.options.bodyReader != nil:
+		args = append(args, "--data-binary", "@-")
+	case len(req.Body()) > 0:
+		body := r.redactBody(req.Body(), req.Header.ContentType())
+		args = append(args, "--data-binary", shellQuote(string(body)))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for POSIX shells
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@%+,") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
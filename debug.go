@@ -0,0 +1,85 @@
+package apifast
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// maxDumpBody is the number of body bytes printed by the debug dump
+const maxDumpBody = 64 << 10
+
+// Debug prints a dump of every request attempt and its response to stderr
+func (c *Client) Debug(enabled bool) *Client {
+	if enabled {
+		return c.DebugWriter(os.Stderr)
+	}
+	c.debug = nil
+	return c
+}
+
+// DebugWriter prints a dump of every request attempt and its response to w:
+// method, URL, headers, body, status and timing. Credentials and any headers
+// and fields named with RedactHeaders and RedactFields are redacted.
+func (c *Client) DebugWriter(w io.Writer) *Client {
+	c.debug = w
+	return c
+}
+
+// dump writes an attempt to the debug writer; resp is nil if the attempt failed
+func (b *FastBuilder) dump(req *fasthttp.Request, resp *fasthttp.Response, elapsed time.Duration, err error) {
+	var buf bytes.Buffer
+	c, r := b.client, b.redactor()
+	fmt.Fprintf(&buf, "> %s %s\n", req.Header.Method(), r.redactURL(req.URI()))
+	req.Header.VisitAll(func(key, value []byte) {
+		fmt.Fprintf(&buf, "> %s: %s\n", key, r.headerValue(key, value))
+	})
+	if b.options.multipart != nil || b.options.bodyReader != nil {
+		buf.WriteString(">\n> (streamed body)\n")
+	} else {
+		dumpBody(&buf, "> ", r.redactBody(req.Body(), req.Header.ContentType()))
+	}
+
+	if err != nil {
+		fmt.Fprintf(&buf, "< error after %s: %v\n\n", elapsed.Round(time.Microsecond), err)
+		c.debug.Write(buf.Bytes())
+		return
+	}
+
+	fmt.Fprintf(&buf, "< %d %s (%s)\n", resp.StatusCode(), fasthttp.StatusMessage(resp.StatusCode()), elapsed.Round(time.Microsecond))
+	resp.Header.VisitAll(func(key, value []byte) {
+		fmt.Fprintf(&buf, "< %s: %s\n", key, r.headerValue(key, value))
+	})
+	if b.streaming() && resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
+		buf.WriteString("<\n< (streamed body)\n")
+	} else {
+		dumpBody(&buf, "< ", r.redactBody(resp.Body(), resp.Header.ContentType()))
+	}
+	buf.WriteString("\n")
+	c.debug.Write(buf.Bytes())
+}
+
+// dumpBody writes a body, truncated to maxDumpBody bytes
+func dumpBody(buf *bytes.Buffer, prefix string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	buf.WriteString(prefix[:1] + "\n")
+	truncated := 0
+	if len(body) > maxDumpBody {
+		truncated = len(body) - maxDumpBody
+		body = body[:maxDumpBody]
+	}
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		buf.WriteString(prefix)
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	if truncated > 0 {
+		fmt.Fprintf(buf, "%s... (%d more bytes)\n", prefix, truncated)
+	}
+}
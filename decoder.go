@@ -0,0 +1,126 @@
+package apifast
+
+import (
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Decoder decodes a response body into v
+type Decoder func(data []byte, v interface{}) error
+
+// defaultDecoders maps media types to the decoders used by every client, next
+// to the JSON and text decoders that depend on the client codec. Keys are full
+// media types, structured syntax suffixes such as "+xml", or type wildcards
+// such as "text/*".
+var defaultDecoders = map[string]Decoder{
+	"application/xml":                   xml.Unmarshal,
+	"text/xml":                          xml.Unmarshal,
+	"+xml":                              xml.Unmarshal,
+	"application/msgpack":               msgpack.Unmarshal,
+	"application/x-msgpack":             msgpack.Unmarshal,
+	"application/vnd.msgpack":           msgpack.Unmarshal,
+	"application/x-protobuf":            unmarshalProto,
+	"application/protobuf":              unmarshalProto,
+	"application/vnd.google.protobuf":   unmarshalProto,
+	"application/x-www-form-urlencoded": unmarshalForm,
+}
+
+// Decoder registers dec for responses of the given media type, overriding the
+// default decoder for it. The media type may also be a structured syntax
+// suffix ("+cbor") or a type wildcard ("text/*").
+func (c *Client) Decoder(mediaType string, dec Decoder) *Client {
+	// Copy on write, so groups never share later registrations
+	decoders := maps.Clone(c.decoders)
+	if decoders == nil {
+		decoders = make(map[string]Decoder)
+	}
+	decoders[strings.ToLower(mediaType)] = dec
+	c.decoders = decoders
+	return c
+}
+
+// decoderFor picks the decoder for a response content type, trying the exact
+// media type, then its suffix, then its type wildcard. Responses without a
+// known content type are decoded as JSON.
+func (c *Client) decoderFor(contentType string) Decoder {
+	dec, _ := c.lookupDecoder(contentType)
+	return dec
+}
+
+// lookupDecoder picks the decoder for a response content type like
+// decoderFor, reporting whether it is the client's JSON codec
+func (c *Client) lookupDecoder(contentType string) (Decoder, bool) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	keys := []string{mediaType}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		keys = append(keys, mediaType[i:])
+	}
+	if main, _, ok := strings.Cut(mediaType, "/"); ok {
+		keys = append(keys, main+"/*")
+	}
+
+	for _, key := range keys {
+		if dec, ok := c.decoders[key]; ok {
+			return dec, false
+		}
+		if dec, ok := c.builtinDecoder(key); ok {
+			return dec, key == "application/json" || key == "+json"
+		}
+	}
+	return c.json().Unmarshal, true
+}
+
+// builtinDecoder returns the default decoder for a registry key
+func (c *Client) builtinDecoder(key string) (Decoder, bool) {
+	switch key {
+	case "application/json", "+json":
+		return c.json().Unmarshal, true
+	case "text/*":
+		return c.unmarshalText, true
+	}
+	dec, ok := defaultDecoders[key]
+	return dec, ok
+}
+
+// unmarshalForm decodes a urlencoded body into *url.Values or *map[string]string
+func unmarshalForm(data []byte, dest interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	switch d := dest.(type) {
+	case *url.Values:
+		*d = values
+	case *map[string][]string:
+		*d = values
+	case *map[string]string:
+		*d = make(map[string]string, len(values))
+		for key := range values {
+			(*d)[key] = values.Get(key)
+		}
+	default:
+		return fmt.Errorf("cannot decode form into %T", dest)
+	}
+	return nil
+}
+
+// unmarshalText copies a text body into *string or *[]byte; other destinations
+// are decoded as JSON, which servers commonly mislabel as text/plain
+func (c *Client) unmarshalText(data []byte, dest interface{}) error {
+	switch d := dest.(type) {
+	case *string:
+		*d = string(data)
+	case *[]byte:
+		*d = append((*d)[:0], data...)
+	default:
+		return c.json().Unmarshal(data, dest)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DialContext opens the client's TCP connections with dial instead of the
+// default dialer, for example to use a custom resolver or an overlay network.
+// It has the signature of net.Dialer.DialContext and receives the "tcp4"
+// network, or "tcp" with dual stack dialing. Dialing through a proxy is not affected.
+func (c *Client) DialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *Client {
+	c.dialContext = dial
+	return c
+}
+
+// ResolveHost connects to one of ips whenever a request targets host, in the
+// order given, bypassing DNS while the URL, Host header and TLS server name
+// keep using host. It helps with canary targeting and split-horizon DNS.
+func (c *Client) ResolveHost(host string, ips ...string) *Client {
+	// Copy on write, so groups never share later overrides
+	resolve := maps.Clone(c.resolve)
+	if resolve == nil {
+		resolve = make(map[string][]string)
+	}
+	resolve[strings.ToLower(host)] = ips
+	c.resolve = resolve
+	return c
+}
+
+// UnixSocket sends every request of the client over the unix domain socket at
+// path, such as /var/run/docker.sock. URLs keep an http scheme and a host, which
+// is only sent in the Host header, for example http://unix/v1.43/containers/json.
+func (c *Client) UnixSocket(path string) *Client {
+	c.unixSocket = path
+	return c
+}
+
+// LocalAddr binds outgoing connections to the local IP address ip, for
+// multi-homed hosts and egress IP pinning
+func (c *Client) LocalAddr(ip string) *Client {
+	c.localAddr = ip
+	return c
+}
+
+// LocalInterface binds outgoing connections to an address of the network
+// interface name, such as eth1, preferring IPv4 addresses
+func (c *Client) LocalInterface(name string) *Client {
+	c.localInterface = name
+	return c
+}
+
+// customDial reports whether connections need the client's own dialer
+func (c *Client) customDial() bool {
+	return c.dialTimeout > 0 || c.dns != nil || c.dialContext != nil || len(c.resolve) > 0 ||
+		c.unixSocket != "" || c.localAddr != "" || c.localInterface != "" ||
+		c.ipFamily != IPv4Only || c.bandwidth != nil || c.timings != nil
+}
+
+// dial opens a TCP connection to addr for the client's connection pool,
+// throttled to the client's bandwidth limit and timed with Timings
+func (c *Client) dial(addr string, timeout time.Duration) (net.Conn, error) {
+	var timing *connTiming
+	if c.timings != nil {
+		timing = &connTiming{}
+	}
+	start := time.Now()
+	conn, err := c.dialHost(addr, timeout, timing)
+	if err != nil {
+		return nil, err
+	}
+	if c.bandwidth != nil {
+		conn = c.bandwidth.wrap(conn)
+	}
+	conn = c.setup.conns.track(conn)
+	if timing != nil {
+		timing.connect = time.Since(start) - timing.dns
+		conn = c.timings.track(conn, timing)
+	}
+	return conn, nil
+}
+
+// dialHost connects to addr, or to the fixed addresses of its host, within
+// the shorter of timeout and the client's DialTimeout, recording the DNS
+// lookup in timing if not nil
+func (c *Client) dialHost(addr string, timeout time.Duration, timing *connTiming) (net.Conn, error) {
+	if c.dialTimeout > 0 && (timeout <= 0 || timeout > c.dialTimeout) {
+		timeout = c.dialTimeout
+	}
+	if timeout <= 0 {
+		timeout = fasthttp.DefaultDialTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if timing != nil {
+		ctx = context.WithValue(ctx, connTimingKey{}, timing)
+	}
+
+	if c.unixSocket != "" {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", c.unixSocket)
+	}
+
+	network := c.network()
+
+	// Fixed addresses replace the resolution of the host
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, ok := c.resolve[strings.ToLower(host)]
+	if !ok {
+		return c.dialAddr(ctx, network, addr)
+	}
+	var errs []error
+	for _, ip := range ips {
+		conn, err := c.dialAddr(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// dialAddr connects to addr through the configured dialer until ctx is done
+func (c *Client) dialAddr(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.dialContext != nil {
+		return c.dialContext(ctx, network, addr)
+	}
+	dialer, err := c.netDialer(network)
+	if err != nil {
+		return nil, err
+	}
+	if c.ipFamily == PreferIPv4 || c.ipFamily == PreferIPv6 {
+		return c.dialPreferred(ctx, dialer, addr)
+	}
+	if c.dns != nil || c.timings != nil {
+		// Timed connections resolve on their own so the lookup can be measured
+		return c.dns.dial(ctx, dialer, network, addr)
+	}
+	if dialer.LocalAddr != nil || network == "tcp6" {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	deadline, _ := ctx.Deadline()
+	if network == "tcp" {
+		return fasthttp.DialDualStackTimeout(addr, time.Until(deadline))
+	}
+	return fasthttp.DialTimeout(addr, time.Until(deadline))
+}
+
+// netDialer returns a dialer bound to the client's local address, if any
+func (c *Client) netDialer(network string) (*net.Dialer, error) {
+	dialer := &net.Dialer{}
+	switch {
+	case c.localAddr != "":
+		ip := net.ParseIP(c.localAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid local address %q", c.localAddr)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	case c.localInterface != "":
+		ip, err := interfaceIP(c.localInterface, network)
+		if err != nil {
+			return nil, err
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	return dialer, nil
+}
+
+// interfaceIP picks the address of the network interface name to bind to,
+// the first IPv4 address or, unless network is tcp4, the first address
+func interfaceIP(name, network string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local interface: %w", err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("invalid local interface: %w", err)
+	}
+
+	var fallback net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+		if fallback == nil && network != "tcp4" {
+			fallback = ipNet.IP
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("local interface %s has no usable address", name)
+	}
+	return fallback, nil
+}
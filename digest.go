@@ -0,0 +1,198 @@
+package apifast
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// digestChallenge is a parsed WWW-Authenticate Digest challenge (RFC 7616)
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	userhash  bool
+	stale     bool
+
+	mu sync.Mutex
+	nc uint32 // nonce count, incremented for every use of the nonce
+}
+
+// parseDigestChallenge parses a Digest challenge, reporting false for other schemes
+func parseDigestChallenge(header string) (*digestChallenge, bool) {
+	scheme, params, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return nil, false
+	}
+
+	ch := &digestChallenge{algorithm: "MD5"}
+	for _, p := range splitDigestParams(params) {
+		key, value, _ := strings.Cut(p, "=")
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "realm":
+			ch.realm = value
+		case "nonce":
+			ch.nonce = value
+		case "opaque":
+			ch.opaque = value
+		case "algorithm":
+			ch.algorithm = strings.ToUpper(value)
+		case "qop":
+			// Prefer plain auth when the server offers several options
+			for _, q := range strings.Split(value, ",") {
+				q = strings.TrimSpace(q)
+				if q == "auth" || (q == "auth-int" && ch.qop == "") {
+					ch.qop = q
+				}
+			}
+		case "userhash":
+			ch.userhash = strings.EqualFold(value, "true")
+		case "stale":
+			ch.stale = strings.EqualFold(value, "true")
+		}
+	}
+	if ch.nonce == "" || ch.hash() == nil {
+		return nil, false
+	}
+	return ch, true
+}
+
+// splitDigestParams splits challenge parameters on commas outside quotes
+func splitDigestParams(s string) []string {
+	var params []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				params = append(params, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(params, s[start:])
+}
+
+// hash returns the hash function for the challenge algorithm, nil if unsupported
+func (ch *digestChallenge) hash() func() hash.Hash {
+	switch strings.TrimSuffix(ch.algorithm, "-SESS") {
+	case "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	case "SHA-512-256":
+		return sha512.New512_256
+	}
+	return nil
+}
+
+// authorize computes the Authorization header value for a request
+func (ch *digestChallenge) authorize(method, uri, username, password string, body []byte) string {
+	newHash := ch.hash()
+	h := func(parts ...string) string {
+		sum := newHash()
+		sum.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	ch.mu.Lock()
+	ch.nc++
+	nc := fmt.Sprintf("%08x", ch.nc)
+	ch.mu.Unlock()
+
+	cnonceBytes := make([]byte, 16)
+	_, _ = rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+
+	ha1 := h(username, ch.realm, password)
+	if strings.HasSuffix(ch.algorithm, "-SESS") {
+		ha1 = h(ha1, ch.nonce, cnonce)
+	}
+	ha2 := h(method, uri)
+	if ch.qop == "auth-int" {
+		ha2 = h(method, uri, h(string(body)))
+	}
+
+	var response string
+	if ch.qop != "" {
+		response = h(ha1, ch.nonce, nc, cnonce, ch.qop, ha2)
+	} else {
+		response = h(ha1, ch.nonce, ha2)
+	}
+
+	if ch.userhash {
+		username = h(username, ch.realm)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		quoteEscaper.Replace(username), quoteEscaper.Replace(ch.realm), ch.nonce, uri, ch.algorithm, response)
+	if ch.qop != "" {
+		fmt.Fprintf(&sb, `, qop=%s, nc=%s, cnonce="%s"`, ch.qop, nc, cnonce)
+	}
+	if ch.opaque != "" {
+		fmt.Fprintf(&sb, `, opaque="%s"`, ch.opaque)
+	}
+	if ch.userhash {
+		sb.WriteString(", userhash=true")
+	}
+	return sb.String()
+}
+
+// digestAuthorize preemptively authenticates req with a challenge cached for its host
+func (c *Client) digestAuthorize(req *fasthttp.Request, auth Auth) bool {
+	cached, ok := c.digestChallenges.Load(string(req.URI().Host()))
+	if !ok {
+		return false
+	}
+	ch := cached.(*digestChallenge)
+	req.Header.Set("Authorization", ch.authorize(string(req.Header.Method()),
+		string(req.URI().RequestURI()), auth.Username, auth.Password, req.Body()))
+	return true
+}
+
+// digestHandshake answers a Digest challenge in resp by re-sending req once with credentials
+func (b *FastBuilder) digestHandshake(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, auth Auth) error {
+	if resp.StatusCode() != fasthttp.StatusUnauthorized || req.BodyStream() != nil {
+		return nil
+	}
+
+	var ch *digestChallenge
+	resp.Header.VisitAll(func(key, value []byte) {
+		if ch == nil && strings.EqualFold(string(key), "WWW-Authenticate") {
+			ch, _ = parseDigestChallenge(string(value))
+		}
+	})
+	if ch == nil {
+		return nil
+	}
+
+	// A request already sent with the cached nonce is only repeated when the nonce went stale
+	host := string(req.URI().Host())
+	if previous, ok := b.client.digestChallenges.Load(host); ok && !ch.stale &&
+		previous.(*digestChallenge).nonce == ch.nonce {
+		return nil
+	}
+	b.client.digestChallenges.Store(host, ch)
+
+	b.client.digestAuthorize(req, auth)
+	streamBody := resp.StreamBody
+	resp.Reset()
+	resp.StreamBody = streamBody
+	return b.client.roundTrip(ctx, req, resp)
+}
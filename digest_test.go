@@ -0,0 +1,191 @@
+package apifast_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eantaru/apifast"
+	"github.com/eantaru/apifast/apifasttest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestDigestAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string // algorithm offered in the challenge, MD5 if empty
+		qop       string // qop offered in the challenge, none if empty
+		userhash  bool
+		wantQOP   string
+	}{
+		{name: "MD5 with auth", qop: "auth", wantQOP: "auth"},
+		{name: "MD5 without qop"},
+		{name: "SHA-256", algorithm: "SHA-256", qop: "auth", wantQOP: "auth"},
+		{name: "MD5 session", algorithm: "MD5-sess", qop: "auth", wantQOP: "auth"},
+		{name: "auth is preferred over auth-int", qop: "auth-int,auth", wantQOP: "auth"},
+		{name: "hashed username", algorithm: "SHA-256", qop: "auth", userhash: true, wantQOP: "auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &digestServer{algorithm: tt.algorithm, qop: tt.qop, userhash: tt.userhash, nonce: "n1"}
+			srv := apifasttest.NewServer(server.handle)
+			defer srv.Close()
+			client := srv.Client.Auth(apifast.Auth{Username: "admin", Password: "secret", Digest: true})
+
+			for i := range 2 {
+				resp, err := client.Build().Uri("/status?verbose=1").Get()
+				if err != nil {
+					t.Fatalf("request %d: Get() error = %v", i, err)
+				}
+				if resp.Code != 200 {
+					t.Fatalf("request %d: status = %d, want 200", i, resp.Code)
+				}
+			}
+			// The challenge is answered once, then the cached one authenticates directly
+			if server.challenges != 1 || server.requests != 3 {
+				t.Errorf("server sent %d challenges over %d requests, want 1 over 3", server.challenges, server.requests)
+			}
+			if server.qopSeen != tt.wantQOP {
+				t.Errorf("qop = %q, want %q", server.qopSeen, tt.wantQOP)
+			}
+		})
+	}
+}
+
+func TestDigestAuthRenewsStaleNonce(t *testing.T) {
+	server := &digestServer{qop: "auth", nonce: "n1"}
+	srv := apifasttest.NewServer(server.handle)
+	defer srv.Close()
+	client := srv.Client.Auth(apifast.Auth{Username: "admin", Password: "secret", Digest: true})
+
+	for i, nonce := range []string{"n1", "n2"} {
+		server.setNonce(nonce)
+		resp, err := client.Build().Uri("/status").Get()
+		if err != nil {
+			t.Fatalf("request %d: Get() error = %v", i, err)
+		}
+		if resp.Code != 200 {
+			t.Fatalf("request %d: status = %d, want 200", i, resp.Code)
+		}
+	}
+	if server.challenges != 2 {
+		t.Errorf("server sent %d challenges, want 2", server.challenges)
+	}
+}
+
+func TestDigestAuthRejectsWrongPassword(t *testing.T) {
+	server := &digestServer{qop: "auth", nonce: "n1"}
+	srv := apifasttest.NewServer(server.handle)
+	defer srv.Close()
+	client := srv.Client.Auth(apifast.Auth{Username: "admin", Password: "wrong", Digest: true})
+
+	_, err := client.Build().Uri("/status").Get()
+	var statusErr *apifast.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != 401 {
+		t.Fatalf("Get() error = %v, want a 401 StatusError", err)
+	}
+	// The challenge is answered once, not again for the same nonce
+	if server.requests != 2 {
+		t.Errorf("sent %d requests, want 2", server.requests)
+	}
+}
+
+// digestServer accepts admin/secret with HTTP Digest authentication
+type digestServer struct {
+	algorithm string
+	qop       string
+	userhash  bool
+
+	mu         sync.Mutex
+	nonce      string
+	stale      bool // the previous nonce was replaced
+	requests   int
+	challenges int
+	qopSeen    string
+}
+
+var digestParam = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|([^,\s]*))`)
+
+func (s *digestServer) setNonce(nonce string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nonce != nonce {
+		s.nonce, s.stale = nonce, true
+	}
+}
+
+func (s *digestServer) handle(ctx *fasthttp.RequestCtx) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	params := map[string]string{}
+	if auth, ok := strings.CutPrefix(string(ctx.Request.Header.Peek("Authorization")), "Digest "); ok {
+		for _, m := range digestParam.FindAllStringSubmatch(auth, -1) {
+			params[m[1]] = m[2] + m[3]
+		}
+	}
+	if params["nonce"] == s.nonce && params["uri"] == string(ctx.RequestURI()) &&
+		params["response"] == s.response(params, string(ctx.Method())) {
+		s.qopSeen = params["qop"]
+		ctx.SetStatusCode(200)
+		return
+	}
+
+	s.challenges++
+	challenge := `Digest realm="api", nonce="` + s.nonce + `", opaque="o"`
+	if s.algorithm != "" {
+		challenge += ", algorithm=" + s.algorithm
+	}
+	if s.qop != "" {
+		challenge += `, qop="` + s.qop + `"`
+	}
+	if s.userhash {
+		challenge += ", userhash=true"
+	}
+	if s.stale && params["nonce"] != "" {
+		challenge += ", stale=true"
+	}
+	ctx.Response.Header.Set("WWW-Authenticate", challenge)
+	ctx.SetStatusCode(401)
+}
+
+// response computes the expected digest for the request parameters
+func (s *digestServer) response(params map[string]string, method string) string {
+	algorithm := s.algorithm
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	newHash := md5.New
+	if strings.HasPrefix(algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(parts ...string) string {
+		sum := newHash()
+		sum.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	username := "admin"
+	if s.userhash {
+		username = h("admin", "api")
+	}
+	if params["username"] != username || params["opaque"] != "o" || !strings.EqualFold(params["algorithm"], algorithm) {
+		return "invalid"
+	}
+
+	ha1 := h("admin", "api", "secret")
+	if strings.HasSuffix(strings.ToLower(algorithm), "-sess") {
+		ha1 = h(ha1, s.nonce, params["cnonce"])
+	}
+	ha2 := h(method, params["uri"])
+	if params["qop"] == "" {
+		return h(ha1, s.nonce, ha2)
+	}
+	return h(ha1, s.nonce, params["nc"], params["cnonce"], params["qop"], ha2)
+}
@@ -0,0 +1,159 @@
+package apifast
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolver discovers the upstream replicas of a service. Upstream URLs hold
+// a scheme and host, such as http://10.0.1.10:8080.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]Upstream, error)
+}
+
+// ResolverFunc adapts a plain function to the Resolver interface
+type ResolverFunc func(ctx context.Context) ([]Upstream, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context) ([]Upstream, error) {
+	return f(ctx)
+}
+
+// Discover keeps the upstreams of the client's load balancer in sync with
+// resolver, refreshing them every interval (30s if zero), so requests follow
+// topology changes. Requests to the base URL are sent to the discovered
+// upstreams, keeping its path. The first lookup runs with the first request;
+// a failed refresh keeps the previous upstreams, and requests go to the
+// base URL itself until a lookup succeeds. Refreshes stop when the client is
+// closed. Without LoadBalance, upstreams take turns.
+func (c *Client) Discover(resolver Resolver, interval time.Duration) *Client {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if c.balancer == nil {
+		c.balancer = &balancer{}
+	}
+	c.discovery = &discovery{resolver: resolver, interval: interval}
+	return c
+}
+
+// discovery refreshes the upstreams of a balancer
+type discovery struct {
+	resolver Resolver
+	interval time.Duration
+}
+
+// startDiscovery runs the first lookup and refreshes the upstreams until the client is closed
+func (c *Client) startDiscovery() {
+	if c.discovery == nil {
+		return
+	}
+	c.balancer.base = strings.TrimRight(c.baseURL, "/")
+	c.refreshUpstreams()
+	go func() {
+		ticker := time.NewTicker(c.discovery.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.setup.closed:
+				return
+			case <-ticker.C:
+				c.refreshUpstreams()
+			}
+		}
+	}()
+}
+
+// refreshUpstreams replaces the upstreams with the outcome of a lookup
+func (c *Client) refreshUpstreams() {
+	ctx, cancel := context.WithTimeout(context.Background(), min(c.discovery.interval, 10*time.Second))
+	defer cancel()
+	upstreams, err := c.discovery.resolver.Resolve(ctx)
+	if err != nil || len(upstreams) == 0 {
+		return
+	}
+
+	// Discovered hosts serve the path of the base URL
+	var path string
+	if base, err := url.Parse(c.baseURL); err == nil {
+		path = strings.TrimRight(base.Path, "/")
+	}
+	rooted := make([]Upstream, len(upstreams))
+	for i, u := range upstreams {
+		rooted[i] = Upstream{URL: strings.TrimRight(u.URL, "/") + path, Weight: u.Weight}
+	}
+	c.balancer.update(rooted)
+}
+
+// DNSSRV discovers upstreams from the DNS SRV records of _service._proto.name,
+// such as DNSSRV("http", "api", "tcp", "example.com"). Records of the lowest
+// priority are used, weighted by their SRV weight.
+func DNSSRV(scheme, service, proto, name string) Resolver {
+	return ResolverFunc(func(ctx context.Context) ([]Upstream, error) {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, service, proto, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var upstreams []Upstream
+		for _, r := range records {
+			if r.Priority != records[0].Priority {
+				break // records are sorted by priority
+			}
+			host := strings.TrimSuffix(r.Target, ".")
+			upstreams = append(upstreams, Upstream{
+				URL:    scheme + "://" + net.JoinHostPort(host, strconv.Itoa(int(r.Port))),
+				Weight: int(r.Weight),
+			})
+		}
+		return upstreams, nil
+	})
+}
+
+// consulEntry is an entry of the Consul health API
+type consulEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+		Weights struct {
+			Passing int
+		}
+	}
+}
+
+// Consul discovers the passing instances of service from the Consul agent at
+// addr, such as Consul("http://127.0.0.1:8500", "http", "orders")
+func Consul(addr, scheme, service string) Resolver {
+	consul := NewClient().BaseURL(addr)
+	return ResolverFunc(func(ctx context.Context) ([]Upstream, error) {
+		var entries []consulEntry
+		_, err := consul.Build().
+			WithContext(ctx).
+			Uri("/v1/health/service/" + url.PathEscape(service) + "?passing=true").
+			Result(&entries).
+			Get()
+		if err != nil {
+			return nil, fmt.Errorf("consul lookup of %s failed: %w", service, err)
+		}
+
+		upstreams := make([]Upstream, 0, len(entries))
+		for _, e := range entries {
+			host := e.Service.Address
+			if host == "" {
+				host = e.Node.Address
+			}
+			upstreams = append(upstreams, Upstream{
+				URL:    scheme + "://" + net.JoinHostPort(host, strconv.Itoa(e.Service.Port)),
+				Weight: e.Service.Weights.Passing,
+			})
+		}
+		return upstreams, nil
+	})
+}
@@ -0,0 +1,130 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DNSCache caches the addresses of resolved hosts for ttl, so new connections
+// skip the resolver round-trip. Connections to a host rotate through its
+// addresses. Without it, fasthttp caches lookups for one minute.
+func (c *Client) DNSCache(ttl time.Duration) *Client {
+	c.dns = &dnsCache{ttl: ttl, hosts: make(map[string]*dnsEntry)}
+	return c
+}
+
+// FlushDNSCache forgets every cached address, so the next connection to each
+// host resolves it again
+func (c *Client) FlushDNSCache() {
+	if c.dns == nil {
+		return
+	}
+	c.dns.mu.Lock()
+	clear(c.dns.hosts)
+	c.dns.mu.Unlock()
+}
+
+// dnsCache holds the resolved addresses of hosts until they expire
+type dnsCache struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*dnsEntry
+}
+
+// dnsEntry is the cached lookup of a single host
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+	next    int // address the next connection starts with
+}
+
+// lookup returns the addresses of host, resolving it on a miss or once the
+// cached lookup expired. The addresses are rotated on every call. A nil cache
+// resolves every time.
+func (d *dnsCache) lookup(ctx context.Context, host, network string) ([]net.IPAddr, error) {
+	if d == nil {
+		return resolve(ctx, host, network)
+	}
+	d.mu.Lock()
+	entry, ok := d.hosts[host]
+	if ok && time.Now().Before(entry.expires) {
+		addrs := rotate(entry.addrs, entry.next)
+		entry.next++
+		d.mu.Unlock()
+		return addrs, nil
+	}
+	d.mu.Unlock()
+
+	addrs, err := resolve(ctx, host, network)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	d.hosts[host] = &dnsEntry{addrs: addrs, expires: time.Now().Add(d.ttl), next: 1}
+	d.mu.Unlock()
+	return addrs, nil
+}
+
+// resolve looks up the addresses of host usable on network
+func resolve(ctx context.Context, host, network string) ([]net.IPAddr, error) {
+	resolved, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := resolved[:0]
+	for _, addr := range resolved {
+		if network == "tcp" || (addr.IP.To4() != nil) == (network == "tcp4") {
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no suitable address found", Name: host}
+	}
+	return addrs, nil
+}
+
+// dial connects to the first reachable address of the host of addr. A nil
+// cache resolves the host every time.
+func (d *dnsCache) dial(ctx context.Context, dialer *net.Dialer, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	start := time.Now()
+	addrs, err := d.lookup(ctx, host, network)
+	recordDNS(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, ip := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// rotate returns addrs starting at index n, wrapping around
+func rotate(addrs []net.IPAddr, n int) []net.IPAddr {
+	n %= len(addrs)
+	if n == 0 {
+		return addrs
+	}
+	rotated := make([]net.IPAddr, 0, len(addrs))
+	return append(append(rotated, addrs[n:]...), addrs[:n]...)
+}
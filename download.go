@@ -0,0 +1,158 @@
+package apifast
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ResultWriter streams the body of successful responses into w instead of buffering it.
+// Bodies of non-2xx responses are still buffered in Response.Body.
+func (b *FastBuilder) ResultWriter(w io.Writer) *FastBuilder {
+	b.writer = w
+	return b
+}
+
+// DownloadToFile streams the body of a successful response into the file at path
+func (b *FastBuilder) DownloadToFile(path string) *FastBuilder {
+	b.downloadPath = path
+	return b
+}
+
+// streaming reports whether the response body should be streamed rather than buffered
+func (b *FastBuilder) streaming() bool {
+	return b.writer != nil || b.downloadPath != ""
+}
+
+// bodyFlusher is implemented by writers that buffer a trailing part of the body
+type bodyFlusher interface {
+	flushBody() error
+}
+
+// writeBody streams the response body into the configured writer or file,
+// verifying its checksums on the way
+func (b *FastBuilder) writeBody(ctx context.Context, resp *fasthttp.Response) error {
+	checks, err := b.headerChecksums(resp)
+	if err != nil {
+		return err
+	}
+	checks = append(checks, b.expectedChecksum()...)
+
+	if b.downloadPath == "" {
+		err := b.client.streamBody(ctx, resp, &checksumWriter{w: b.writer, checks: checks})
+		if flusher, ok := b.writer.(bodyFlusher); ok && err == nil {
+			err = flusher.flushBody()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write response body: %w", err)
+		}
+		return checkDigests(checks)
+	}
+
+	f, err := os.Create(b.downloadPath)
+	if err != nil {
+		return fmt.Errorf("failed to create download file: %w", err)
+	}
+	err = b.client.streamBody(ctx, resp, &checksumWriter{w: f, checks: checks})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(b.downloadPath)
+		return fmt.Errorf("failed to write response body: %w", err)
+	}
+	if err := checkDigests(checks); err != nil {
+		os.Remove(b.downloadPath)
+		return err
+	}
+	return nil
+}
+
+// streamBody copies the response body into w, stopping early once ctx is done
+func (c *Client) streamBody(ctx context.Context, resp *fasthttp.Response, w io.Writer) error {
+	r := resp.BodyStream()
+	if r == nil {
+		_, err := w.Write(resp.Body())
+		return err
+	}
+
+	// A read blocked on an idle connection only returns once the connection
+	// is closed; a failed copy keeps it out of the pool. resp is released
+	// once the abort has finished.
+	addr := resp.LocalAddr()
+	aborted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(aborted)
+		if closer, ok := r.(io.Closer); ok {
+			closer.Close()
+			return
+		}
+		c.setup.conns.close(addr)
+	})
+	defer func() {
+		if !stop() {
+			<-aborted
+		}
+	}()
+	err := resp.BodyWriteTo(&contextWriter{ctx: ctx, w: w})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// contextWriter fails writes once its context is done
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
+// connRegistry tracks the open TCP connections of a client by local address
+type connRegistry struct {
+	conns sync.Map // local address -> *trackedConn
+}
+
+// track registers conn until it is closed
+func (r *connRegistry) track(conn net.Conn) net.Conn {
+	addr, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return conn
+	}
+	tc := &trackedConn{Conn: conn, registry: r, key: addr.String()}
+	r.conns.Store(tc.key, tc)
+	return tc
+}
+
+// close closes the tracked connection with the local address addr, if any
+func (r *connRegistry) close(addr net.Addr) {
+	if addr == nil {
+		return
+	}
+	if tc, ok := r.conns.Load(addr.String()); ok {
+		tc.(*trackedConn).Close()
+	}
+}
+
+// trackedConn removes itself from its registry once closed
+type trackedConn struct {
+	net.Conn
+	registry *connRegistry
+	key      string
+}
+
+func (c *trackedConn) Close() error {
+	c.registry.conns.CompareAndDelete(c.key, c)
+	return c.Conn.Close()
+}
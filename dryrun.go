@@ -0,0 +1,47 @@
+package apifast
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/valyala/fasthttp"
+)
+
+// PreparedRequest is a request as it would have been sent by a dry run
+type PreparedRequest struct {
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    []byte // nil for PayloadReader bodies, which are left unread; read from the file for PayloadFile
+}
+
+// DryRun builds the request, runs the client's request hooks and returns the
+// result in Response.Request instead of sending it. Nothing reaches the
+// network, rate limits and circuit breakers are not consulted, and the
+// Response carries no status.
+func (b *FastBuilder) DryRun(enabled bool) *FastBuilder {
+	b.dryRun = enabled
+	return b
+}
+
+// prepared captures req as a PreparedRequest
+func (b *FastBuilder) prepared(req *fasthttp.Request) *PreparedRequest {
+	headers := make(http.Header)
+	req.Header.VisitAll(func(key, value []byte) {
+		headers.Add(string(key), string(value))
+	})
+	p := &PreparedRequest{
+		Method:  string(req.Header.Method()),
+		URL:     req.URI().String(),
+		Headers: headers,
+	}
+	switch {
+	case b.payloadFile != "":
+		// Read the file itself, leaving the opened body stream unread
+		p.Body, _ = os.ReadFile(b.payloadFile)
+	case b.options.bodyReader == nil:
+		// Multipart bodies are encoded here, opening their files
+		p.Body = append([]byte(nil), req.Body()...)
+	}
+	return p
+}
@@ -0,0 +1,90 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	// ErrTimeout is returned when a request exceeds its timeout or deadline
+	ErrTimeout = errors.New("request timed out")
+
+	// ErrCanceled is returned when the request context is canceled; the
+	// error also matches context.Canceled
+	ErrCanceled = errors.New("request canceled")
+
+	// ErrRateLimited is returned when the client-side rate limit rejects a request
+	ErrRateLimited = errors.New("request rejected by client-side rate limit")
+
+	// ErrCircuitOpen is returned while the circuit breaker of the target host is open
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+
+	// ErrConcurrencyLimit is returned when the client's concurrency limit
+	// rejects a request with MaxConcurrentFailFast
+	ErrConcurrencyLimit = errors.New("request rejected by client-side concurrency limit")
+
+	// ErrChecksumMismatch is returned when a response body does not match its
+	// expected or announced checksum
+	ErrChecksumMismatch = errors.New("response checksum mismatch")
+
+	// ErrBodyTooLarge is returned when a response body exceeds the client's
+	// MaxResponseBodySize
+	ErrBodyTooLarge = errors.New("response body too large")
+)
+
+// ConnError reports a failure to connect to or exchange data with the server
+type ConnError struct {
+	Method    string // Request method
+	URL       string // Request URL
+	Err       error  // Underlying transport error
+	RequestID string // ID sent with the request, if the client sends one
+}
+
+func (e *ConnError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("request failed: %s %s (request id %s): %v", e.Method, e.URL, e.RequestID, e.Err)
+	}
+	return fmt.Sprintf("request failed: %s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *ConnError) Unwrap() error { return e.Err }
+
+// StatusError is returned alongside the Response when the server answers
+// with a status code of 400 or above
+type StatusError struct {
+	Code      int    // HTTP code
+	Body      []byte // Response body
+	RequestID string // ID sent with the request, if the client sends one
+}
+
+func (e *StatusError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("unexpected status %d %s (request id %s)", e.Code, fasthttp.StatusMessage(e.Code), e.RequestID)
+	}
+	return fmt.Sprintf("unexpected status %d %s", e.Code, fasthttp.StatusMessage(e.Code))
+}
+
+// wrapError converts a transport failure into one of the exported error types
+func (b *FastBuilder) wrapError(err error) error {
+	// Errors raised by hooks and builder steps are returned as is
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return permanent.err
+	}
+
+	// Check if the error is due to a timeout or cancellation
+	var netErr net.Error
+	if errors.Is(err, fasthttp.ErrTimeout) || errors.Is(err, fasthttp.ErrDialTimeout) ||
+		errors.Is(err, fasthttp.ErrTLSHandshakeTimeout) ||
+		errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	}
+	return &ConnError{Method: b.method, URL: b.client.resolveURL(b.url), Err: err, RequestID: b.requestID}
+}
@@ -0,0 +1,61 @@
+package apifast
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// expectContinueTimeout is how long a request waits for 100 Continue before
+// sending its body anyway, for servers that ignore the expectation
+const expectContinueTimeout = time.Second
+
+// expectContinue holds the settings of ExpectContinue
+type expectContinue struct {
+	minSize int64
+}
+
+// ExpectContinue sends request bodies of at least minSize bytes, and streamed
+// bodies of unknown size, with an Expect: 100-continue header. The body is only
+// transmitted once the server answers 100 Continue, or after a second without
+// an answer, so uploads the server rejects early, say with 401 or 413, waste
+// no bandwidth. fasthttp cannot wait for the interim response, so with the
+// HTTP1 protocol these requests go through a separate net/http connection
+// pool. A custom Transport and HTTP3 do not support the expectation.
+func (c *Client) ExpectContinue(minSize int64) *Client {
+	c.expect = &expectContinue{minSize: minSize}
+	return c
+}
+
+// configureExpect sets up the transport of requests expecting 100 Continue
+func (c *Client) configureExpect() {
+	if c.expect == nil || c.protocol != HTTP1 {
+		return
+	}
+	t := c.httpTransport()
+	t.ForceAttemptHTTP2 = false
+	t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	c.pool.expect = newStdTransport(t, c.hc.MaxResponseBodySize)
+}
+
+// expectBody adds the Expect: 100-continue header to req if its body is large enough
+func (c *Client) expectBody(req *fasthttp.Request) {
+	if c.expect == nil {
+		return
+	}
+	size := int64(len(req.Body()))
+	if req.IsBodyStream() {
+		size = int64(req.Header.ContentLength())
+	}
+	if size < 0 || (size > 0 && size >= c.expect.minSize) {
+		req.Header.Set(fasthttp.HeaderExpect, "100-continue")
+	}
+}
+
+// expects reports whether req waits for 100 Continue before sending its body
+func expects(req *fasthttp.Request) bool {
+	return bytes.EqualFold(req.Header.Peek(fasthttp.HeaderExpect), []byte("100-continue"))
+}
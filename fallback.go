@@ -0,0 +1,58 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Fallback adds secondary hosts that take over when the base URL fails. A
+// call whose attempts end in a connection error, a timeout, a 5xx response or
+// an open circuit is sent again under each fallback base URL in turn, keeping
+// the rest of its URL, until one succeeds. Each fallback gets the full retry
+// policy. Calls to other hosts, and calls that are not idempotent and carry no
+// idempotency key, stay on their host. Call Fallback after BaseURL.
+func (c *Client) Fallback(urls ...string) *Client {
+	c.fallback = &fallback{base: strings.TrimRight(c.baseURL, "/")}
+	for _, url := range urls {
+		c.fallback.urls = append(c.fallback.urls, strings.TrimRight(url, "/"))
+	}
+	return c
+}
+
+// fallback holds the base URLs tried after the primary one
+type fallback struct {
+	base string
+	urls []string
+}
+
+// failover sends the call to the fallback hosts while its outcome calls for it
+func (b *FastBuilder) failover(ctx context.Context, resp *Response, err error) (*Response, error) {
+	f := b.client.fallback
+	if f == nil || f.base == "" || !underBase(b.client.resolveURL(b.url), f.base) {
+		return resp, err
+	}
+	for _, url := range f.urls {
+		if !b.failed(ctx, resp, err) || !b.rewindBody() {
+			break
+		}
+		resp, err = b.retry(withRebase(ctx, f.base, url))
+	}
+	return resp, err
+}
+
+// failed reports whether an outcome should move the call to the next host
+func (b *FastBuilder) failed(ctx context.Context, resp *Response, err error) bool {
+	if ctx.Err() != nil || b.dryRun {
+		return false
+	}
+	// Another host may apply a call the failed one already processed
+	if !b.idempotent() {
+		return false
+	}
+	if err != nil {
+		var permanent *permanentError
+		return errors.Is(err, ErrCircuitOpen) || !errors.As(err, &permanent)
+	}
+	return resp.Code >= 500
+}
@@ -0,0 +1,110 @@
+package apifast_test
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestFallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		url       string
+		key       string   // Idempotency-Key of the call
+		wantHosts []string // hosts the call is sent to, in order
+	}{
+		{
+			name:      "GET fails over to each host in turn",
+			method:    "GET",
+			url:       "/orders",
+			wantHosts: []string{"api.example.com", "eu.example.com", "backup.example.com"},
+		},
+		{
+			name:      "POST stays on the primary host",
+			method:    "POST",
+			url:       "/orders",
+			wantHosts: []string{"api.example.com"},
+		},
+		{
+			name:      "POST with an Idempotency-Key fails over",
+			method:    "POST",
+			url:       "/orders",
+			key:       "key-1",
+			wantHosts: []string{"api.example.com", "eu.example.com", "backup.example.com"},
+		},
+		{
+			name:      "calls to other hosts are not moved or repeated",
+			method:    "GET",
+			url:       "https://other.example.com/orders",
+			wantHosts: []string{"other.example.com"},
+		},
+		{
+			name:      "hosts sharing the prefix are not moved",
+			method:    "GET",
+			url:       "https://api.example.com.evil/orders",
+			wantHosts: []string{"api.example.com.evil"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On(tt.method, "*").Reply(503, "")
+			client := apifast.NewClient().
+				BaseURL("https://api.example.com").
+				Transport(mock).
+				Fallback("https://eu.example.com", "https://backup.example.com")
+
+			b := client.Build().Uri(tt.url)
+			if tt.key != "" {
+				b.IdempotencyKey(tt.key)
+			}
+			if _, err := b.Method(tt.method).Do(); err == nil {
+				t.Fatalf("%s succeeded, want the 503 of the last host", tt.method)
+			}
+
+			var hosts []string
+			for _, r := range mock.Requests() {
+				hosts = append(hosts, requestHost(t, r.URL))
+			}
+			if !slices.Equal(hosts, tt.wantHosts) {
+				t.Errorf("sent to %q, want %q", hosts, tt.wantHosts)
+			}
+		})
+	}
+}
+
+func TestFallbackKeepsPathBelowBase(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("GET", "https://api.example.com/v1/orders*").Fail(errors.New("connection refused"))
+	mock.On("GET", "*").Reply(200, "ok")
+	client := apifast.NewClient().
+		BaseURL("https://api.example.com/v1").
+		Transport(mock).
+		Fallback("https://eu.example.com/v2")
+
+	resp, err := client.Build().Uri("/orders?page=2").Get()
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if resp.Code != 200 {
+		t.Fatalf("status = %d, want 200", resp.Code)
+	}
+	requests := mock.Requests()
+	if got := requests[len(requests)-1].URL; got != "https://eu.example.com/v2/orders?page=2" {
+		t.Errorf("fallback request sent to %s", got)
+	}
+}
+
+// requestHost returns the host of rawURL
+func requestHost(t *testing.T, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("invalid request URL %q: %v", rawURL, err)
+	}
+	return u.Host
+}
@@ -0,0 +1,99 @@
+package apifast
+
+import (
+	"context"
+	"time"
+)
+
+// Option customizes the FastBuilder used by the generic request helpers
+type Option func(b *FastBuilder)
+
+// WithClient sends the request through c instead of the default client
+func WithClient(c *Client) Option {
+	return func(b *FastBuilder) {
+		b.client = c
+	}
+}
+
+// WithHeaders sets custom headers for the request
+func WithHeaders(headers []Header) Option {
+	return func(b *FastBuilder) {
+		b.Headers(headers)
+	}
+}
+
+// WithAuth sets the authentication options
+func WithAuth(auth Auth) Option {
+	return func(b *FastBuilder) {
+		b.Auth(auth)
+	}
+}
+
+// WithTimeout sets the request timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(b *FastBuilder) {
+		b.Timeout(timeout)
+	}
+}
+
+// WithAttemptTimeout sets the timeout of each individual attempt
+func WithAttemptTimeout(timeout time.Duration) Option {
+	return func(b *FastBuilder) {
+		b.AttemptTimeout(timeout)
+	}
+}
+
+// WithPayloadJSON marshals v as the request payload
+func WithPayloadJSON(v interface{}) Option {
+	return func(b *FastBuilder) {
+		b.PayloadJSON(v)
+	}
+}
+
+// WithStrictJSON rejects unknown fields when decoding the response
+func WithStrictJSON() Option {
+	return func(b *FastBuilder) {
+		b.StrictJSON()
+	}
+}
+
+// Do sends the request configured on b and decodes a successful response into a T
+func Do[T any](b *FastBuilder) (T, *Response, error) {
+	var result T
+	resp, err := b.Result(&result).Do()
+	return result, resp, err
+}
+
+// Get sends a GET request to url and decodes the response into a T
+func Get[T any](ctx context.Context, url string, opts ...Option) (T, *Response, error) {
+	return send[T](ctx, "GET", url, opts)
+}
+
+// Post sends a POST request to url and decodes the response into a T
+func Post[T any](ctx context.Context, url string, opts ...Option) (T, *Response, error) {
+	return send[T](ctx, "POST", url, opts)
+}
+
+// Put sends a PUT request to url and decodes the response into a T
+func Put[T any](ctx context.Context, url string, opts ...Option) (T, *Response, error) {
+	return send[T](ctx, "PUT", url, opts)
+}
+
+// Patch sends a PATCH request to url and decodes the response into a T
+func Patch[T any](ctx context.Context, url string, opts ...Option) (T, *Response, error) {
+	return send[T](ctx, "PATCH", url, opts)
+}
+
+// Delete sends a DELETE request to url and decodes the response into a T
+func Delete[T any](ctx context.Context, url string, opts ...Option) (T, *Response, error) {
+	return send[T](ctx, "DELETE", url, opts)
+}
+
+// send builds a request from the options and runs it through Do
+func send[T any](ctx context.Context, method, url string, opts []Option) (T, *Response, error) {
+	b := Build().WithContext(ctx).Uri(url).Method(method)
+	for _, opt := range opts {
+		opt(b)
+	}
+	return Do[T](b)
+}
@@ -0,0 +1,72 @@
+package apifast
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GraphQLError is a single entry of the errors list of a GraphQL response
+type GraphQLError struct {
+	Message   string                 `json:"message"`
+	Path      []interface{}          `json:"path,omitempty"`
+	Locations []GraphQLErrorLocation `json:"locations,omitempty"`
+	// Extensions carries implementation specific details such as error codes
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLErrorLocation points at the part of the query an error relates to
+type GraphQLErrorLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLErrors is returned alongside the Response when a GraphQL response
+// carries errors; any partial data is still decoded into the Result
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+// graphqlRequest is the standard GraphQL over HTTP request body
+type graphqlRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// graphqlResponse is the standard GraphQL over HTTP response body
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// GraphQL sets a GraphQL query with its variables as the payload. The data
+// field of the response is decoded into the Result, and errors reported by the
+// server are returned as GraphQLErrors. Send it with Post.
+func (b *FastBuilder) GraphQL(query string, variables map[string]interface{}) *FastBuilder {
+	b.envelope = b.decodeGraphQL
+	b.options.Headers = append(b.options.Headers, Header{Tag: "Accept", Value: "application/json"})
+	return b.PayloadJSON(graphqlRequest{Query: query, Variables: variables})
+}
+
+// decodeGraphQL splits a GraphQL response into the result and its errors
+func (b *FastBuilder) decodeGraphQL(body []byte) error {
+	var envelope graphqlResponse
+	if err := b.client.json().Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("failed to decode GraphQL response: %w", err)
+	}
+	if b.result != nil && len(envelope.Data) > 0 && string(envelope.Data) != "null" {
+		if err := b.client.json().Unmarshal(envelope.Data, b.result); err != nil {
+			return err
+		}
+	}
+	if len(envelope.Errors) > 0 {
+		return envelope.Errors
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package apifast
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/valyala/fasthttp"
+)
+
+// HARRecorder captures the traffic of a client in HAR 1.2 format, for
+// inspection in browser devtools or replay tooling. It is safe for concurrent use.
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// NewHARRecorder creates an empty recorder
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// RecordHAR records every request attempt and its response into rec
+func (c *Client) RecordHAR(rec *HARRecorder) *Client {
+	c.har = rec
+	return c
+}
+
+// WriteTo writes the recorded traffic to w as a HAR document
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := r.MarshalJSON()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// MarshalJSON encodes the recorded traffic as a HAR document
+func (r *HARRecorder) MarshalJSON() ([]byte, error) {
+	r.mu.Lock()
+	entries := append([]harEntry{}, r.entries...)
+	r.mu.Unlock()
+
+	var doc harDocument
+	doc.Log.Version = "1.2"
+	doc.Log.Creator = harCreator{Name: "apifast", Version: "1"}
+	doc.Log.Entries = entries
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Reset drops the recorded traffic
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+// record adds an attempt; resp is nil if the attempt failed
+func (r *HARRecorder) record(b *FastBuilder, req *fasthttp.Request, resp *fasthttp.Response, start time.Time, elapsed time.Duration, err error) {
+	rd := b.redactor()
+	ms := float64(elapsed.Microseconds()) / 1000
+	entry := harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: harRequest{
+			Method:      string(req.Header.Method()),
+			URL:         rd.redactURL(req.URI()),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     rd.harHeaders(&req.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(req.Body()),
+		},
+		Response: harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HTTPVersion: "HTTP/1.1",
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Cache:   struct{}{},
+		Timings: harTimings{Send: 0, Wait: ms, Receive: 0},
+	}
+	req.URI().QueryArgs().VisitAll(func(key, value []byte) {
+		if rd.redactsField(string(key)) {
+			value = []byte(redacted)
+		}
+		entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: string(key), Value: string(value)})
+	})
+	if b.options.multipart != nil || b.options.bodyReader != nil {
+		entry.Request.BodySize = -1
+		entry.Request.PostData = &harPostData{MimeType: string(req.Header.ContentType())}
+	} else if body := req.Body(); len(body) > 0 {
+		entry.Request.PostData = &harPostData{MimeType: string(req.Header.ContentType()), Text: string(rd.redactBody(body, req.Header.ContentType()))}
+	}
+
+	if err != nil {
+		entry.Response.Content = harContent{Size: 0}
+		entry.Response.Error = err.Error()
+	} else {
+		entry.Response.Status = resp.StatusCode()
+		entry.Response.StatusText = fasthttp.StatusMessage(resp.StatusCode())
+		entry.Response.Headers = rd.harHeaders(&resp.Header)
+		entry.Response.RedirectURL = string(resp.Header.Peek(fasthttp.HeaderLocation))
+		entry.Response.Content.MimeType = string(resp.Header.ContentType())
+		if !(b.streaming() && resp.StatusCode() >= 200 && resp.StatusCode() < 300) {
+			body := resp.Body()
+			entry.Response.BodySize = len(body)
+			entry.Response.Content.Size = len(body)
+			body = rd.redactBody(body, resp.Header.ContentType())
+			if utf8.Valid(body) {
+				entry.Response.Content.Text = string(body)
+			} else {
+				entry.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+				entry.Response.Content.Encoding = "base64"
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+}
+
+// harHeaders lists the headers of a request or response, redacted
+func (r redactor) harHeaders(h interface {
+	VisitAll(func(key, value []byte))
+}) []harNameValue {
+	headers := []harNameValue{}
+	h.VisitAll(func(key, value []byte) {
+		headers = append(headers, harNameValue{Name: string(key), Value: r.headerValue(key, value)})
+	})
+	return headers
+}
+
+type harDocument struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	Error       string         `json:"_error,omitempty"` // custom field for failed attempts
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
@@ -0,0 +1,170 @@
+package apifast_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+// harEntry is the part of a HAR entry checked by the tests
+type harEntry struct {
+	Request struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		PostData    *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			Size     int    `json:"size"`
+			Text     string `json:"text"`
+			Encoding string `json:"encoding"`
+		} `json:"content"`
+		Error string `json:"_error"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func TestHARRecorder(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("GET", "/items").Reply(200, "ok")
+	mock.On("POST", "/items").Reply(201, "created")
+	mock.On("GET", "/logo").Reply(200, "\xff\x00")
+	mock.On("GET", "/down").Fail(errors.New("connection refused"))
+	rec := apifast.NewHARRecorder()
+	client := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock).RecordHAR(rec)
+
+	tests := []struct {
+		name  string
+		send  func(b *apifast.FastBuilder) error
+		check func(t *testing.T, e harEntry)
+	}{
+		{
+			name: "credentials are redacted",
+			send: func(b *apifast.FastBuilder) error {
+				_, err := b.Uri("/items?page=2&access_token=abc").Auth(apifast.Auth{Token: "secret"}).Get()
+				return err
+			},
+			check: func(t *testing.T, e harEntry) {
+				if e.Request.Method != "GET" || e.Response.Status != 200 || e.Response.Content.Text != "ok" {
+					t.Errorf("entry = %s %d %q, want GET 200 %q", e.Request.Method, e.Response.Status, e.Response.Content.Text, "ok")
+				}
+				want := []harNameValue{{"page", "2"}, {"access_token", "[REDACTED]"}}
+				if len(e.Request.QueryString) != 2 || e.Request.QueryString[0] != want[0] || e.Request.QueryString[1] != want[1] {
+					t.Errorf("queryString = %+v, want %+v", e.Request.QueryString, want)
+				}
+				authorization := ""
+				for _, h := range e.Request.Headers {
+					if h.Name == "Authorization" {
+						authorization = h.Value
+					}
+				}
+				if authorization != "[REDACTED]" {
+					t.Errorf("Authorization recorded as %q, want [REDACTED]", authorization)
+				}
+			},
+		},
+		{
+			name: "request body is recorded redacted",
+			send: func(b *apifast.FastBuilder) error {
+				_, err := b.Uri("/items").PayloadJSON(map[string]string{"name": "a", "client_secret": "s3cret"}).Post()
+				return err
+			},
+			check: func(t *testing.T, e harEntry) {
+				if e.Request.PostData == nil {
+					t.Fatal("postData missing")
+				}
+				if want := `{"client_secret":"[REDACTED]","name":"a"}`; e.Request.PostData.Text != want {
+					t.Errorf("postData = %q, want %q", e.Request.PostData.Text, want)
+				}
+				if e.Response.Status != 201 {
+					t.Errorf("status = %d, want 201", e.Response.Status)
+				}
+			},
+		},
+		{
+			name: "binary response is base64",
+			send: func(b *apifast.FastBuilder) error {
+				_, err := b.Uri("/logo").Get()
+				return err
+			},
+			check: func(t *testing.T, e harEntry) {
+				if e.Response.Content.Encoding != "base64" || e.Response.Content.Text != "/wA=" || e.Response.Content.Size != 2 {
+					t.Errorf("content = %+v, want 2 bytes in base64 %q", e.Response.Content, "/wA=")
+				}
+			},
+		},
+		{
+			name: "failed attempt records the error",
+			send: func(b *apifast.FastBuilder) error {
+				if _, err := b.Uri("/down").Get(); err == nil {
+					return errors.New("request to /down succeeded")
+				}
+				return nil
+			},
+			check: func(t *testing.T, e harEntry) {
+				if e.Response.Status != 0 || e.Response.Error == "" {
+					t.Errorf("response = %d with error %q, want no status and an error", e.Response.Status, e.Response.Error)
+				}
+			},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.send(client.Build()); err != nil {
+				t.Fatalf("send error = %v", err)
+			}
+			entries := harEntries(t, rec)
+			if len(entries) != i+1 {
+				t.Fatalf("recorded %d entries, want %d", len(entries), i+1)
+			}
+			tt.check(t, entries[i])
+		})
+	}
+
+	var buf bytes.Buffer
+	if _, err := rec.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if data, _ := rec.MarshalJSON(); !bytes.Equal(buf.Bytes(), data) {
+		t.Error("WriteTo() and MarshalJSON() differ")
+	}
+	rec.Reset()
+	if entries := harEntries(t, rec); len(entries) != 0 {
+		t.Errorf("%d entries left after Reset()", len(entries))
+	}
+}
+
+// harEntries decodes the entries recorded by rec
+func harEntries(t *testing.T, rec *apifast.HARRecorder) []harEntry {
+	t.Helper()
+	data, err := rec.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+	var doc struct {
+		Log struct {
+			Version string     `json:"version"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid HAR document: %v", err)
+	}
+	if doc.Log.Version != "1.2" {
+		t.Errorf("HAR version = %q, want 1.2", doc.Log.Version)
+	}
+	return doc.Log.Entries
+}
@@ -0,0 +1,113 @@
+package apifast
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// HealthCheckSettings configures the active health checks of load balanced upstreams
+type HealthCheckSettings struct {
+	Path               string                         // Path probed with GET on every upstream, the base URL itself if empty
+	Interval           time.Duration                  // Time between probes of an upstream, 10s if zero
+	Timeout            time.Duration                  // Timeout of a probe, 2s if zero
+	UnhealthyThreshold int                            // Consecutive failed probes that evict an upstream, 2 if zero
+	HealthyThreshold   int                            // Consecutive passed probes that reinstate it, 2 if zero
+	OnStateChange      func(url string, healthy bool) // Called when an upstream is evicted or reinstated
+}
+
+// HealthCheck probes the upstreams of LoadBalance in the background and
+// takes those failing repeatedly out of the rotation until they recover. A
+// probe passes on a 2xx or 3xx status. When every upstream is unhealthy,
+// requests are spread across all of them rather than failing. Probes start
+// with the first request and stop when the client is closed.
+func (c *Client) HealthCheck(settings HealthCheckSettings) *Client {
+	if settings.Interval <= 0 {
+		settings.Interval = 10 * time.Second
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 2 * time.Second
+	}
+	if settings.UnhealthyThreshold <= 0 {
+		settings.UnhealthyThreshold = 2
+	}
+	if settings.HealthyThreshold <= 0 {
+		settings.HealthyThreshold = 2
+	}
+	c.healthCheck = &settings
+	return c
+}
+
+// startHealthChecks probes the upstreams on every interval until the client is closed
+func (c *Client) startHealthChecks() {
+	if c.healthCheck == nil || c.balancer == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(c.healthCheck.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.setup.closed:
+				return
+			case <-ticker.C:
+			}
+
+			var wg sync.WaitGroup
+			for _, u := range c.balancer.snapshot() {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.checkHealth(u)
+				}()
+			}
+			wg.Wait()
+		}
+	}()
+}
+
+// checkHealth probes u and updates its health
+func (c *Client) checkHealth(u *upstream) {
+	settings := c.healthCheck
+	passed := c.probe(u)
+
+	c.balancer.mu.Lock()
+	changed := false
+	if passed {
+		u.failedProbes = 0
+		u.passedProbes++
+		if !u.healthy() && u.passedProbes >= settings.HealthyThreshold {
+			u.evicted, changed = false, true
+		}
+	} else {
+		u.passedProbes = 0
+		u.failedProbes++
+		if u.healthy() && u.failedProbes >= settings.UnhealthyThreshold {
+			u.evicted, changed = true, true
+		}
+	}
+	healthy := u.healthy()
+	c.balancer.mu.Unlock()
+
+	if changed && settings.OnStateChange != nil {
+		settings.OnStateChange(u.url, healthy)
+	}
+}
+
+// probe sends a single health check request to u
+func (c *Client) probe(u *upstream) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), c.healthCheck.Timeout)
+	defer cancel()
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(joinURL(u.url, c.healthCheck.Path))
+	if err := c.do(ctx, req, resp); err != nil {
+		return false
+	}
+	return resp.StatusCode() < 400
+}
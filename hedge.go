@@ -0,0 +1,82 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// HedgePolicy controls hedged requests: duplicates of a slow request sent
+// after a delay, where the first good response wins and the others are canceled
+type HedgePolicy struct {
+	Delay       time.Duration // Wait before firing the next duplicate
+	MaxAttempts int           // Maximum number of concurrent copies, including the original
+}
+
+// Hedge fires a duplicate of the request whenever no response arrived within
+// delay, up to maxAttempts copies in total. Hedging is skipped for streamed
+// request or response bodies, which cannot be duplicated, and for requests
+// that are not idempotent, whose side effects would be repeated.
+func (b *FastBuilder) Hedge(delay time.Duration, maxAttempts int) *FastBuilder {
+	b.options.Hedge = HedgePolicy{Delay: delay, MaxAttempts: maxAttempts}
+	return b
+}
+
+// hedgeable reports whether the request can be sent as concurrent copies
+func (b *FastBuilder) hedgeable() bool {
+	return b.options.Hedge.MaxAttempts > 1 && b.options.bodyReader == nil && !b.streaming() &&
+		(b.options.multipart == nil || !b.options.multipart.hasReaders()) && b.idempotent()
+}
+
+// hedge sends staggered copies of the request and returns the first good response
+func (b *FastBuilder) hedge(ctx context.Context) (*Response, error) {
+	// Losing copies are canceled once a winner is returned
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		resp *Response
+		err  error
+	}
+	policy := b.options.Hedge
+	outcomes := make(chan outcome, policy.MaxAttempts)
+	launched, pending := 0, 0
+	launch := func() {
+		launched++
+		pending++
+		go func() {
+			resp, err := b.send(ctx)
+			outcomes <- outcome{resp, err}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(policy.Delay)
+	defer timer.Stop()
+
+	var last outcome
+	for {
+		select {
+		case o := <-outcomes:
+			pending--
+			var permanent *permanentError
+			if (o.err == nil && o.resp.Code < 500) || errors.As(o.err, &permanent) {
+				return o.resp, o.err
+			}
+			last = o
+			if pending == 0 {
+				if launched >= policy.MaxAttempts {
+					return last.resp, last.err
+				}
+				// Every copy failed, hedge again without waiting
+				launch()
+				timer.Reset(policy.Delay)
+			}
+		case <-timer.C:
+			if launched < policy.MaxAttempts {
+				launch()
+				timer.Reset(policy.Delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package apifast_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestHedge(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		delay        time.Duration // hedging delay
+		firstStatus  int
+		firstDelay   time.Duration // delay of the first copy, answering "first"
+		restDelay    time.Duration // delay of the later copies, answering "hedge"
+		wantBody     string
+		wantRequests int
+	}{
+		{"fast response is not hedged", "GET", 20 * time.Millisecond, 200, 0, 0, "first", 1},
+		{"slow response is hedged", "GET", 20 * time.Millisecond, 200, time.Second, 0, "hedge", 2},
+		{"failed copy is hedged without waiting", "GET", time.Second, 503, 0, 0, "hedge", 2},
+		{"copies are capped", "GET", 20 * time.Millisecond, 200, 100 * time.Millisecond, 100 * time.Millisecond, "first", 3},
+		{"POST is not hedged", "POST", 20 * time.Millisecond, 200, 100 * time.Millisecond, 0, "first", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On(tt.method, "/search").Reply(tt.firstStatus, "first").Delay(tt.firstDelay).Times(1)
+			mock.On(tt.method, "/search").Reply(200, "hedge").Delay(tt.restDelay)
+			client := apifast.NewClient().Transport(mock)
+
+			start := time.Now()
+			resp, err := client.Build().Uri("https://api.example.com/search").Hedge(tt.delay, 3).Method(tt.method).Do()
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("call took %v, want the first good response", elapsed)
+			}
+			if string(resp.Body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", resp.Body, tt.wantBody)
+			}
+			if got := len(mock.Requests()); got != tt.wantRequests {
+				t.Errorf("sent %d copies, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package apifast_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestHMACSigner(t *testing.T) {
+	tests := []struct {
+		name            string
+		signer          apifast.HMACSigner
+		signatureHeader string
+		timestampHeader string
+		encode          func([]byte) string
+	}{
+		{
+			name:            "defaults",
+			signer:          apifast.HMACSigner{Secret: []byte("secret")},
+			signatureHeader: "X-Signature",
+			timestampHeader: "X-Timestamp",
+			encode:          hex.EncodeToString,
+		},
+		{
+			name: "custom headers, base64 and prefix",
+			signer: apifast.HMACSigner{
+				Secret:          []byte("secret"),
+				SignatureHeader: "X-Sig",
+				TimestampHeader: "X-Ts",
+				Base64:          true,
+				Prefix:          "sha256=",
+			},
+			signatureHeader: "X-Sig",
+			timestampHeader: "X-Ts",
+			encode:          func(b []byte) string { return "sha256=" + base64.StdEncoding.EncodeToString(b) },
+		},
+		{
+			name:            "custom hash",
+			signer:          apifast.HMACSigner{Secret: []byte("secret"), Hash: sha1.New},
+			signatureHeader: "X-Signature",
+			timestampHeader: "X-Timestamp",
+			encode:          hex.EncodeToString,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On("POST", "/orders").Reply(200, "")
+			client := apifast.NewClient().Transport(mock).OnBeforeRequest(tt.signer.Sign)
+
+			before := time.Now().Unix()
+			_, err := client.Build().Uri("https://api.example.com/orders?page=2").Payload([]byte(`{"id":1}`)).Post()
+			if err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+
+			r := mock.Requests()[0]
+			timestamp := r.Header.Get(tt.timestampHeader)
+			if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || ts < before || ts > time.Now().Unix() {
+				t.Errorf("%s = %q, want the current unix time", tt.timestampHeader, timestamp)
+			}
+			newHash := tt.signer.Hash
+			if newHash == nil {
+				newHash = sha256.New
+			}
+			mac := hmac.New(newHash, []byte("secret"))
+			mac.Write([]byte(strings.Join([]string{"POST", "/orders?page=2", timestamp, `{"id":1}`}, "\n")))
+			if want := tt.encode(mac.Sum(nil)); r.Header.Get(tt.signatureHeader) != want {
+				t.Errorf("%s = %q, want %q", tt.signatureHeader, r.Header.Get(tt.signatureHeader), want)
+			}
+		})
+	}
+}
+
+func TestHMACSignerRejectsStreamedBody(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("POST", "/upload").Reply(200, "")
+	client := apifast.NewClient().Transport(mock).OnBeforeRequest(apifast.HMACSigner{Secret: []byte("secret")}.Sign)
+
+	_, err := client.Build().Uri("https://api.example.com/upload").PayloadReader(strings.NewReader("data"), 4).Post()
+	if err == nil || !strings.Contains(err.Error(), "buffered request body") {
+		t.Errorf("Post() error = %v, want a buffered body error", err)
+	}
+	if len(mock.Requests()) != 0 {
+		t.Error("an unsigned request was sent")
+	}
+}
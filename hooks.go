@@ -0,0 +1,61 @@
+package apifast
+
+import (
+	"context"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RequestHook is called with the fully prepared request right before it is sent.
+// Returning an error aborts the request without retrying.
+type RequestHook func(ctx context.Context, req *fasthttp.Request) error
+
+// ResponseHook is called with the response of every attempt before it is returned.
+// Returning an error aborts the request without retrying.
+type ResponseHook func(ctx context.Context, resp *Response) error
+
+// OnBeforeRequest appends a hook to the chain run before each request is sent
+func (c *Client) OnBeforeRequest(hook RequestHook) *Client {
+	c.beforeRequest = append(c.beforeRequest, hook)
+	return c
+}
+
+// OnAfterResponse appends a hook to the chain run after each response is received
+func (c *Client) OnAfterResponse(hook ResponseHook) *Client {
+	c.afterResponse = append(c.afterResponse, hook)
+	return c
+}
+
+// runBeforeRequest runs the request hooks in registration order, returning
+// the names of the headers they added
+func (c *Client) runBeforeRequest(ctx context.Context, req *fasthttp.Request) ([]string, error) {
+	if len(c.beforeRequest) == 0 {
+		return nil, nil
+	}
+	before := make(map[string]bool)
+	req.Header.VisitAll(func(key, _ []byte) { before[string(key)] = true })
+	for _, hook := range c.beforeRequest {
+		if err := hook(ctx, req); err != nil {
+			return nil, &permanentError{err}
+		}
+	}
+
+	// Headers added by hooks, such as signatures, are scoped to the request host
+	var added []string
+	req.Header.VisitAll(func(key, _ []byte) {
+		if !before[string(key)] {
+			added = append(added, string(key))
+		}
+	})
+	return added, nil
+}
+
+// runAfterResponse runs the response hooks in registration order
+func (c *Client) runAfterResponse(ctx context.Context, resp *Response) error {
+	for _, hook := range c.afterResponse {
+		if err := hook(ctx, resp); err != nil {
+			return &permanentError{err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package apifast
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Host sends host in the Host header instead of the host of the URI, which is
+// still the one connected to. It targets a virtual host through a load
+// balancer addressed by IP, or before DNS points at the new servers.
+// Redirects to other hosts drop the override.
+func (b *FastBuilder) Host(host string) *FastBuilder {
+	b.host = host
+	return b
+}
+
+// SNI sets the TLS server name sent in the handshake and verified against the
+// server certificate, instead of the host of the URI. Connections are pooled
+// per client, so the server name applies to every https request of the client;
+// FastBuilder.SNI sets it for one request.
+func (c *Client) SNI(serverName string) *Client {
+	c.tlsConfig().ServerName = serverName
+	return c
+}
+
+// SNI sets the TLS server name of the request, overriding the client's. The
+// request is sent over connections pooled for the host and server name.
+// Redirects to other hosts drop the override; it needs the fasthttp pool, so
+// custom transports, HTTP2, HTTP3 and ExpectContinue requests ignore it.
+func (b *FastBuilder) SNI(serverName string) *FastBuilder {
+	b.sni = serverName
+	return b
+}
+
+// serverNameKey is the context key of the TLS server name of an attempt
+type serverNameKey struct{}
+
+// serverName is the TLS server name used for connections to host
+type serverName struct {
+	host, name string
+}
+
+// withServerName returns a context sending requests to host with the TLS server name name
+func withServerName(ctx context.Context, host, name string) context.Context {
+	return context.WithValue(ctx, serverNameKey{}, serverName{host: host, name: name})
+}
+
+// serverNameHost returns the transport sending req with the TLS server name
+// of ctx, p itself if there is none for the host of req
+func (p *poolTransport) serverNameHost(ctx context.Context, req *fasthttp.Request) Transport {
+	sn, ok := ctx.Value(serverNameKey{}).(serverName)
+	uri := req.URI()
+	if !ok || string(uri.Host()) != sn.host || !bytes.Equal(uri.Scheme(), []byte("https")) ||
+		p.std != nil || (p.expect != nil && expects(req)) {
+		return p
+	}
+
+	addr := fasthttp.AddMissingPort(sn.host, true)
+	key := addr + " " + sn.name
+	if hc, ok := p.serverNames.Load(key); ok {
+		return hc.(*fasthttp.HostClient)
+	}
+	tlsConfig := &tls.Config{}
+	if p.hc.TLSConfig != nil {
+		tlsConfig = p.hc.TLSConfig.Clone()
+	}
+	tlsConfig.ServerName = sn.name
+	hc := p.newHostClient(addr, true, tlsConfig)
+	if p.hc.ConfigureClient != nil {
+		if err := p.hc.ConfigureClient(hc); err != nil {
+			return TransportFunc(func(*fasthttp.Request, *fasthttp.Response, time.Time) error {
+				return fmt.Errorf("failed to configure host client: %w", err)
+			})
+		}
+	}
+	actual, _ := p.serverNames.LoadOrStore(key, hc)
+	return actual.(*fasthttp.HostClient)
+}
@@ -0,0 +1,82 @@
+package apifast_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestSNI(t *testing.T) {
+	tests := []struct {
+		name      string
+		clientSNI string
+		sni       []string // server name set on each request, in order
+		want      []string // server names received in the handshakes
+	}{
+		{
+			name: "no server name is sent for an IP address",
+			sni:  []string{""},
+			want: []string{""},
+		},
+		{
+			name: "the request sets the server name",
+			sni:  []string{"example.com"},
+			want: []string{"example.com"},
+		},
+		{
+			name:      "the request overrides the client",
+			clientSNI: "a.example.com",
+			sni:       []string{"example.com", ""},
+			want:      []string{"example.com", "a.example.com"},
+		},
+		{
+			name: "connections are pooled per server name",
+			sni:  []string{"example.com", "", "example.com", ""},
+			want: []string{"example.com", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var handshakes []string
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			srv.TLS = &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				mu.Lock()
+				handshakes = append(handshakes, hello.ServerName)
+				mu.Unlock()
+				return nil, nil
+			}}
+			srv.StartTLS()
+			defer srv.Close()
+
+			// The test certificate is valid for 127.0.0.1, example.com and its subdomains
+			roots := x509.NewCertPool()
+			roots.AddCert(srv.Certificate())
+			client := apifast.NewClient().RootCAs(roots)
+			if tt.clientSNI != "" {
+				client.SNI(tt.clientSNI)
+			}
+
+			for i, sni := range tt.sni {
+				b := client.Build().Uri(srv.URL + "/health")
+				if sni != "" {
+					b.SNI(sni)
+				}
+				if _, err := b.Get(); err != nil {
+					t.Fatalf("request %d: Get() error = %v", i, err)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(handshakes, tt.want) {
+				t.Errorf("handshakes with server names %q, want %q", handshakes, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package apifast
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// poolTransport is the default transport of a client. Requests to the host of
+// the base URL go straight to a dedicated fasthttp.HostClient, skipping the
+// per-request host lookup of fasthttp.Client; other hosts use the generic client.
+type poolTransport struct {
+	hc *fasthttp.Client
+
+	host   *fasthttp.HostClient     // nil until configured with a base URL
+	pipe   *fasthttp.PipelineClient // replaces host when pipelining
+	std    Transport                // replaces every fasthttp client for other protocols
+	expect Transport                // sends requests expecting 100 Continue
+	addr   []byte                   // host of the base URL, as found in request URIs
+	isTLS  bool
+
+	serverNames sync.Map // "addr name" -> *fasthttp.HostClient of requests setting SNI
+}
+
+// configureHost creates the host client of the base URL, copying the settings of hc
+func (p *poolTransport) configureHost(baseURL string) error {
+	if baseURL == "" {
+		return nil
+	}
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+	if err := uri.Parse(nil, []byte(baseURL)); err != nil {
+		return nil
+	}
+	scheme := string(uri.Scheme())
+	if scheme != "http" && scheme != "https" {
+		return nil
+	}
+
+	p.isTLS = scheme == "https"
+	p.addr = append([]byte(nil), uri.Host()...)
+	p.host = p.newHostClient(fasthttp.AddMissingPort(string(p.addr), p.isTLS), p.isTLS, p.hc.TLSConfig)
+	if p.hc.ConfigureClient != nil {
+		return p.hc.ConfigureClient(p.host)
+	}
+	return nil
+}
+
+// newHostClient creates a host client for addr with the settings of hc
+func (p *poolTransport) newHostClient(addr string, isTLS bool, tlsConfig *tls.Config) *fasthttp.HostClient {
+	return &fasthttp.HostClient{
+		Addr:                          addr,
+		Name:                          p.hc.Name,
+		NoDefaultUserAgentHeader:      p.hc.NoDefaultUserAgentHeader,
+		Dial:                          p.hc.Dial,
+		DialTimeout:                   p.hc.DialTimeout,
+		DialDualStack:                 p.hc.DialDualStack,
+		IsTLS:                         isTLS,
+		TLSConfig:                     tlsConfig,
+		MaxConns:                      p.hc.MaxConnsPerHost,
+		MaxIdleConnDuration:           p.hc.MaxIdleConnDuration,
+		MaxConnDuration:               p.hc.MaxConnDuration,
+		MaxIdemponentCallAttempts:     p.hc.MaxIdemponentCallAttempts,
+		ReadBufferSize:                p.hc.ReadBufferSize,
+		WriteBufferSize:               p.hc.WriteBufferSize,
+		ReadTimeout:                   p.hc.ReadTimeout,
+		WriteTimeout:                  p.hc.WriteTimeout,
+		MaxResponseBodySize:           p.hc.MaxResponseBodySize,
+		DisableHeaderNamesNormalizing: p.hc.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        p.hc.DisablePathNormalizing,
+		MaxConnWaitTimeout:            p.hc.MaxConnWaitTimeout,
+		RetryIf:                       p.hc.RetryIf,
+		RetryIfErr:                    p.hc.RetryIfErr,
+		ConnPoolStrategy:              p.hc.ConnPoolStrategy,
+		StreamResponseBody:            p.hc.StreamResponseBody,
+	}
+}
+
+// configurePipeline creates the pipelined client of the base URL host
+func (p *poolTransport) configurePipeline(settings PipelineSettings) error {
+	if p.host == nil {
+		return errors.New("pipelining requires an http or https base URL")
+	}
+
+	dial := p.hc.Dial
+	if dial == nil && p.hc.DialTimeout != nil {
+		dialTimeout := p.hc.DialTimeout
+		dial = func(addr string) (net.Conn, error) { return dialTimeout(addr, 0) }
+	}
+	p.pipe = &fasthttp.PipelineClient{
+		Addr:                          p.host.Addr,
+		Name:                          p.hc.Name,
+		NoDefaultUserAgentHeader:      p.hc.NoDefaultUserAgentHeader,
+		Dial:                          dial,
+		DialDualStack:                 p.hc.DialDualStack,
+		IsTLS:                         p.isTLS,
+		TLSConfig:                     p.hc.TLSConfig,
+		MaxConns:                      settings.MaxConns,
+		MaxPendingRequests:            settings.MaxPendingRequests,
+		MaxBatchDelay:                 settings.MaxBatchDelay,
+		MaxIdleConnDuration:           p.hc.MaxIdleConnDuration,
+		ReadBufferSize:                p.hc.ReadBufferSize,
+		WriteBufferSize:               p.hc.WriteBufferSize,
+		ReadTimeout:                   p.hc.ReadTimeout,
+		WriteTimeout:                  p.hc.WriteTimeout,
+		DisableHeaderNamesNormalizing: p.hc.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        p.hc.DisablePathNormalizing,
+	}
+	return nil
+}
+
+// route returns the transport serving the host of req
+func (p *poolTransport) route(req *fasthttp.Request, resp *fasthttp.Response) Transport {
+	if p.std != nil {
+		return p.std
+	}
+	if p.expect != nil && expects(req) {
+		return p.expect
+	}
+	if p.host == nil {
+		return p.hc
+	}
+	uri := req.URI()
+	if bytes.Equal(uri.Scheme(), []byte("https")) != p.isTLS || !bytes.Equal(uri.Host(), p.addr) {
+		return p.hc
+	}
+	if p.pipe != nil && !resp.StreamBody {
+		return p.pipe
+	}
+	return p.host
+}
+
+func (p *poolTransport) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return p.route(req, resp).Do(req, resp)
+}
+
+func (p *poolTransport) DoDeadline(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	return p.route(req, resp).DoDeadline(req, resp, deadline)
+}
@@ -0,0 +1,56 @@
+package apifast
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// IdempotencyKeys attaches a random Idempotency-Key header to requests whose
+// method is not idempotent, such as POST and PATCH. Each call gets a new key
+// that stays the same across its retries, so a server honouring it applies
+// the call once.
+func (c *Client) IdempotencyKeys() *Client {
+	c.idempotencyKeys = true
+	return c
+}
+
+// IdempotencyKey sends key in the Idempotency-Key header, reused across retries
+func (b *FastBuilder) IdempotencyKey(key string) *FastBuilder {
+	b.idempotencyKey = key
+	return b
+}
+
+// assignIdempotencyKey generates the key of the call if the client sends one
+func (b *FastBuilder) assignIdempotencyKey() {
+	if !b.client.idempotencyKeys || b.idempotencyKey != "" || idempotentMethod(b.method) {
+		return
+	}
+	for _, h := range b.options.Headers {
+		if strings.EqualFold(h.Tag, "Idempotency-Key") {
+			return
+		}
+	}
+	b.idempotencyKey = newUUID()
+}
+
+// idempotentMethod reports whether sending method more than once has the same effect as once
+func idempotentMethod(method string) bool {
+	switch method {
+	case fasthttp.MethodGet, fasthttp.MethodHead, fasthttp.MethodOptions, fasthttp.MethodTrace,
+		fasthttp.MethodPut, fasthttp.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// newUUID generates a random version 4 UUID
+func newUUID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
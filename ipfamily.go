@@ -0,0 +1,146 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// IPFamily selects the IP versions used to connect to hosts
+type IPFamily int
+
+const (
+	IPv4Only   IPFamily = iota // Only connect over IPv4, the fasthttp default
+	IPv6Only                   // Only connect over IPv6
+	PreferIPv4                 // Try IPv4 first, racing IPv6 if it is slow or fails
+	PreferIPv6                 // Try IPv6 first, racing IPv4 if it is slow or fails
+)
+
+// fallbackDelay is how long the preferred family gets before the other races it (RFC 6555)
+const fallbackDelay = 300 * time.Millisecond
+
+// IPFamily controls whether connections use IPv4, IPv6 or both. With a
+// preference, the preferred family is tried first and the other one joins
+// after 300ms or as soon as the first fails, happy eyeballs style, so broken
+// routes of one family do not hang requests.
+func (c *Client) IPFamily(family IPFamily) *Client {
+	c.ipFamily = family
+	return c
+}
+
+// network returns the network connections are dialed on
+func (c *Client) network() string {
+	switch c.ipFamily {
+	case IPv6Only:
+		return "tcp6"
+	case PreferIPv4, PreferIPv6:
+		return "tcp"
+	}
+	if c.hc.DialDualStack {
+		return "tcp"
+	}
+	return "tcp4"
+}
+
+// dialPreferred connects to the host of addr, racing the preferred IP family
+// against the other one
+func (c *Client) dialPreferred(ctx context.Context, dialer *net.Dialer, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	start := time.Now()
+	var ips []net.IPAddr
+	if c.dns != nil {
+		ips, err = c.dns.lookup(ctx, host, "tcp")
+	} else {
+		ips, err = net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+	recordDNS(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	primary, fallback := splitFamilies(ips, c.ipFamily == PreferIPv6)
+	if len(primary) == 0 || len(fallback) == 0 {
+		return dialSerial(ctx, dialer, append(primary, fallback...), port)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, 2)
+	race := func(ips []net.IPAddr) {
+		go func() {
+			conn, err := dialSerial(ctx, dialer, ips, port)
+			results <- result{conn, err}
+		}()
+	}
+
+	race(primary)
+	pending, fallbackStarted := 1, false
+	timer := time.NewTimer(fallbackDelay)
+	defer timer.Stop()
+
+	var errs []error
+	for pending > 0 || !fallbackStarted {
+		select {
+		case <-timer.C:
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				// Close the connection of a losing attempt that still completes
+				go func(n int) {
+					for ; n > 0; n-- {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			errs = append(errs, r.err)
+		}
+		if !fallbackStarted {
+			fallbackStarted = true
+			pending++
+			race(fallback)
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// splitFamilies separates ips into the preferred family and the other one
+func splitFamilies(ips []net.IPAddr, preferIPv6 bool) (primary, fallback []net.IPAddr) {
+	for _, ip := range ips {
+		if (ip.IP.To4() == nil) == preferIPv6 {
+			primary = append(primary, ip)
+		} else {
+			fallback = append(fallback, ip)
+		}
+	}
+	return primary, fallback
+}
+
+// dialSerial tries ips in order until one accepts the connection
+func dialSerial(ctx context.Context, dialer *net.Dialer, ips []net.IPAddr, port string) (net.Conn, error) {
+	var errs []error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,121 @@
+package apifast
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrRPCNoResponse is set on batch calls the server did not answer
+var ErrRPCNoResponse = errors.New("jsonrpc: no response for call")
+
+// rpcID generates request ids unique within the process
+var rpcID atomic.Int64
+
+// RPCError is the error object of a JSON-RPC 2.0 response
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (code %d)", e.Message, e.Code)
+}
+
+// RPCCall is a single call of a JSON-RPC batch. Result receives the decoded
+// result and Err the error reported for the call once the batch completes.
+type RPCCall struct {
+	Method string
+	Params interface{}
+	Result interface{}
+	Err    error
+
+	id int64
+}
+
+// rpcRequest is the JSON-RPC 2.0 request object
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// rpcResponse is the JSON-RPC 2.0 response object
+type rpcResponse struct {
+	ID     *int64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// decode maps the result into dest or returns the reported error
+func (r *rpcResponse) decode(codec JSONCodec, dest interface{}) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if dest != nil && len(r.Result) > 0 {
+		return codec.Unmarshal(r.Result, dest)
+	}
+	return nil
+}
+
+// JSONRPC sets a JSON-RPC 2.0 call of method as the payload. The result of the
+// response is decoded into the Result, and an error object is returned as an
+// *RPCError. Send it with Post.
+func (b *FastBuilder) JSONRPC(method string, params interface{}) *FastBuilder {
+	b.options.Headers = append(b.options.Headers, Header{Tag: "Accept", Value: "application/json"})
+	b.envelope = func(body []byte) error {
+		var resp rpcResponse
+		if err := b.client.json().Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("failed to decode JSON-RPC response: %w", err)
+		}
+		return resp.decode(b.client.json(), b.result)
+	}
+	return b.PayloadJSON(rpcRequest{JSONRPC: "2.0", ID: rpcID.Add(1), Method: method, Params: params})
+}
+
+// JSONRPCBatch sets calls as a JSON-RPC 2.0 batch payload. Responses are
+// matched to their calls by id, and each call's Result and Err are set once
+// the request completes. Send it with Post.
+func (b *FastBuilder) JSONRPCBatch(calls ...*RPCCall) *FastBuilder {
+	batch := make([]rpcRequest, len(calls))
+	for i, call := range calls {
+		call.id = rpcID.Add(1)
+		batch[i] = rpcRequest{JSONRPC: "2.0", ID: call.id, Method: call.Method, Params: call.Params}
+	}
+
+	b.options.Headers = append(b.options.Headers, Header{Tag: "Accept", Value: "application/json"})
+	b.envelope = func(body []byte) error {
+		// A batch the server cannot process is answered with a single error
+		if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '{' {
+			var resp rpcResponse
+			if err := b.client.json().Unmarshal(body, &resp); err != nil {
+				return fmt.Errorf("failed to decode JSON-RPC response: %w", err)
+			}
+			return resp.decode(b.client.json(), nil)
+		}
+
+		var responses []rpcResponse
+		if err := b.client.json().Unmarshal(body, &responses); err != nil {
+			return fmt.Errorf("failed to decode JSON-RPC batch response: %w", err)
+		}
+		byID := make(map[int64]*rpcResponse, len(responses))
+		for i := range responses {
+			if responses[i].ID != nil {
+				byID[*responses[i].ID] = &responses[i]
+			}
+		}
+		for _, call := range calls {
+			if resp, ok := byID[call.id]; ok {
+				call.Err = resp.decode(b.client.json(), call.Result)
+			} else {
+				call.Err = ErrRPCNoResponse
+			}
+		}
+		return nil
+	}
+	return b.PayloadJSON(batch)
+}
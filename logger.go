@@ -0,0 +1,143 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogLevel is the severity of a request lifecycle event
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota // Request started
+	LogInfo                  // Request completed
+	LogWarn                  // Request is being retried
+	LogError                 // Request failed
+)
+
+// Field is a key/value pair attached to a log event
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives the request lifecycle events of a client
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, fields ...Field)
+}
+
+// Logger emits request lifecycle events (start, retry, error, completion)
+// through l
+func (c *Client) Logger(l Logger) *Client {
+	c.logger = l
+	return c
+}
+
+// SlogLogger adapts a log/slog logger
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct{ l *slog.Logger }
+
+func (s slogLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...Field) {
+	lvl := slog.LevelDebug
+	switch level {
+	case LogInfo:
+		lvl = slog.LevelInfo
+	case LogWarn:
+		lvl = slog.LevelWarn
+	case LogError:
+		lvl = slog.LevelError
+	}
+	if !s.l.Enabled(ctx, lvl) {
+		return
+	}
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	s.l.LogAttrs(ctx, lvl, msg, attrs...)
+}
+
+// ZapLogger adapts a zap logger
+func ZapLogger(l *zap.Logger) Logger {
+	return zapLogger{l}
+}
+
+type zapLogger struct{ l *zap.Logger }
+
+func (z zapLogger) Log(_ context.Context, level LogLevel, msg string, fields ...Field) {
+	lvl := zapcore.DebugLevel
+	switch level {
+	case LogInfo:
+		lvl = zapcore.InfoLevel
+	case LogWarn:
+		lvl = zapcore.WarnLevel
+	case LogError:
+		lvl = zapcore.ErrorLevel
+	}
+	ce := z.l.Check(lvl, msg)
+	if ce == nil {
+		return
+	}
+	zfs := make([]zap.Field, len(fields))
+	for i, f := range fields {
+		zfs[i] = zap.Any(f.Key, f.Value)
+	}
+	ce.Write(zfs...)
+}
+
+// log emits an event about the request through the client's logger
+func (b *FastBuilder) log(level LogLevel, msg string, fields ...Field) {
+	ctx := b.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if b.requestID != "" {
+		ctx = WithRequestID(ctx, b.requestID)
+		fields = append([]Field{{Key: "request_id", Value: b.requestID}}, fields...)
+	}
+	fields = append([]Field{{Key: "method", Value: b.method}, {Key: "url", Value: b.logURL()}}, fields...)
+	b.client.logger.Log(ctx, level, msg, fields...)
+}
+
+// logOutcome emits the completion or failure of the request
+func (b *FastBuilder) logOutcome(response *Response, err error, elapsed time.Duration) {
+	if err == nil {
+		b.log(LogInfo, "request completed", Field{Key: "status", Value: response.Code}, Field{Key: "duration", Value: elapsed})
+		return
+	}
+	fields := []Field{{Key: "error", Value: err.Error()}, {Key: "duration", Value: elapsed}}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		fields = append(fields, Field{Key: "status", Value: statusErr.Code})
+	}
+	b.log(LogError, "request failed", fields...)
+}
+
+// logRetry emits the retry of a failed attempt
+func (b *FastBuilder) logRetry(attempt int, resp *Response, err error) {
+	fields := []Field{{Key: "attempt", Value: attempt}}
+	if err != nil {
+		fields = append(fields, Field{Key: "error", Value: err.Error()})
+	} else {
+		fields = append(fields, Field{Key: "status", Value: resp.Code})
+	}
+	b.log(LogWarn, "retrying request", fields...)
+}
+
+// logURL returns the request URL with redacted query parameters hidden
+func (b *FastBuilder) logURL() string {
+	var uri fasthttp.URI
+	if err := uri.Parse(nil, []byte(b.client.resolveURL(b.url))); err != nil {
+		return b.client.resolveURL(b.url)
+	}
+	return b.redactor().redactURL(&uri)
+}
@@ -0,0 +1,141 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ErrStopPolling can be returned by a long poll callback to stop polling without an error
+var ErrStopPolling = errors.New("stop polling")
+
+// LongPollSettings controls how a long polling endpoint is consumed
+type LongPollSettings struct {
+	Wait         time.Duration // Server-side wait asked for with every request, 30s if zero
+	WaitParam    string        // Query parameter receiving the wait in seconds; a Prefer: wait header is sent if empty
+	CursorHeader string        // Response header holding the cursor of the next request, e.g. X-Consul-Index
+	CursorField  string        // JSON field holding the cursor, dot-separated for nested fields, if CursorHeader is empty
+	CursorParam  string        // Query parameter receiving the cursor on the next request
+	Backoff      Backoff       // Delay schedule after failed requests, DefaultBackoff if nil
+}
+
+// LongPoll keeps sending the request to a long polling endpoint, asking the
+// server to hold it for up to Wait, and calls fn with every response that has
+// a body. Empty, 204 and 304 responses and timed out requests are re-sent at
+// once; network errors, 429 and 5xx responses are re-sent after the backoff or
+// the server's Retry-After. Other error statuses end polling with a
+// *StatusError. If a Result is set it is decoded anew before fn is called.
+// LongPoll returns when fn fails, with nil for ErrStopPolling, or when the
+// context set by WithContext ends.
+func (b *FastBuilder) LongPoll(settings LongPollSettings, fn func(resp *Response) error) error {
+	if settings.Wait <= 0 {
+		settings.Wait = 30 * time.Second
+	}
+	backoff := settings.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	if b.method == "" {
+		b.method = "GET"
+	}
+	ctx := b.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var cursor string
+	for failures := 0; ; {
+		conn, err := b.longPollRequest(settings, cursor)
+		if err != nil {
+			return err
+		}
+		resp, err := conn.makeRequest()
+
+		var statusErr *StatusError
+		switch {
+		case ctx.Err() != nil:
+			return b.wrapError(ctx.Err())
+		case errors.Is(err, ErrTimeout):
+			// The server held the request past our deadline
+			continue
+		case errors.As(err, &statusErr) && statusErr.Code != 429 && statusErr.Code < 500:
+			return err
+		case err != nil:
+			failures++
+			delay := backoff.Delay(failures)
+			if wait, ok := retryAfter(resp); ok {
+				delay = wait
+			}
+			if err := sleep(ctx, delay); err != nil {
+				return b.wrapError(err)
+			}
+			continue
+		}
+		failures = 0
+
+		// Follow the cursor even when nothing new arrived
+		if next, err := longPollCursor(settings, resp); err != nil {
+			return err
+		} else if next != "" {
+			cursor = next
+		}
+		if resp.Code == 204 || resp.Code == 304 || len(resp.Body) == 0 {
+			continue
+		}
+
+		if b.result != nil {
+			if err := b.decodeResult(resp); err != nil {
+				return err
+			}
+		}
+		if err := fn(resp); err != nil {
+			if errors.Is(err, ErrStopPolling) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// longPollRequest returns a copy of the builder asking the server to wait,
+// with the cursor of the previous response if any
+func (b *FastBuilder) longPollRequest(settings LongPollSettings, cursor string) (*FastBuilder, error) {
+	conn := *b
+	// Results are decoded by LongPoll, since empty bodies would not decode
+	conn.result = nil
+	if conn.options.Timeout <= 0 {
+		conn.options.Timeout = settings.Wait + 10*time.Second
+	}
+
+	u, err := url.Parse(b.client.resolveURL(b.url))
+	if err != nil {
+		return nil, err
+	}
+	wait := strconv.Itoa(int(settings.Wait / time.Second))
+	query := u.Query()
+	if settings.WaitParam != "" {
+		query.Set(settings.WaitParam, wait)
+	} else {
+		conn.options.Headers = append(slices.Clip(b.options.Headers), Header{Tag: "Prefer", Value: "wait=" + wait})
+	}
+	if cursor != "" && settings.CursorParam != "" {
+		query.Set(settings.CursorParam, cursor)
+	}
+	u.RawQuery = query.Encode()
+	conn.url = u.String()
+	return &conn, nil
+}
+
+// longPollCursor reads the cursor of the next request from resp, "" if none
+func longPollCursor(settings LongPollSettings, resp *Response) (string, error) {
+	if settings.CursorHeader != "" {
+		return resp.Headers.Get(settings.CursorHeader), nil
+	}
+	if settings.CursorField == "" || len(resp.Body) == 0 {
+		return "", nil
+	}
+	return jsonCursor(resp, settings.CursorField)
+}
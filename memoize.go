@@ -0,0 +1,115 @@
+package apifast
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Memoize caches the decoded result of a successful GET call in-process for
+// ttl, so identical calls of the client, with the same URL, headers and
+// authentication, return it without a request. The result is copied
+// shallowly, so maps, slices and pointers in it are shared between callers
+// and must not be modified.
+func (b *FastBuilder) Memoize(ttl time.Duration) *FastBuilder {
+	b.memoTTL = ttl
+	return b
+}
+
+// InvalidateMemo drops the memoized results of uri, resolved against the base URL
+func (c *Client) InvalidateMemo(uri string) *Client {
+	c.memo.invalidate(c.resolveURL(uri))
+	return c
+}
+
+// ClearMemo drops every memoized result of the client and its groups
+func (c *Client) ClearMemo() *Client {
+	c.memo.invalidate("")
+	return c
+}
+
+// memoCache holds memoized results by call key
+type memoCache struct {
+	mu      sync.Mutex
+	entries map[string]memoEntry
+}
+
+// memoEntry is a memoized response with a copy of its decoded result
+type memoEntry struct {
+	url     string
+	resp    *Response
+	result  reflect.Value // invalid if the call had no result
+	expires time.Time
+}
+
+// memoizable reports whether the outcome of the call may be memoized
+func (b *FastBuilder) memoizable() bool {
+	return b.memoTTL > 0 && b.method == fasthttp.MethodGet && !b.streaming() && !b.dryRun && b.envelope == nil
+}
+
+// memoized returns the memoized response of the call, copying its result into
+// the call's result, if an unexpired one fits
+func (b *FastBuilder) memoized() (*Response, bool) {
+	if !b.memoizable() {
+		return nil, false
+	}
+	m := b.client.memo
+	m.mu.Lock()
+	entry, ok := m.entries[b.flightKey()]
+	m.mu.Unlock()
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	if b.result != nil {
+		dest := reflect.ValueOf(b.result)
+		if dest.Kind() != reflect.Pointer || dest.IsNil() || !entry.result.IsValid() || entry.result.Type() != dest.Elem().Type() {
+			return nil, false
+		}
+		dest.Elem().Set(entry.result)
+	}
+	return entry.resp.share(), true
+}
+
+// memoize stores the response and decoded result of a successful call
+func (b *FastBuilder) memoize(resp *Response) {
+	if !b.memoizable() {
+		return
+	}
+	entry := memoEntry{
+		url:     b.client.resolveURL(b.url),
+		resp:    resp.share(),
+		expires: time.Now().Add(b.memoTTL),
+	}
+	if b.result != nil {
+		if src := reflect.ValueOf(b.result); src.Kind() == reflect.Pointer && !src.IsNil() {
+			entry.result = reflect.New(src.Elem().Type()).Elem()
+			entry.result.Set(src.Elem())
+		}
+	}
+
+	m := b.client.memo
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Expired entries are dropped as new ones arrive
+	now := time.Now()
+	for key, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, key)
+		}
+	}
+	m.entries[b.flightKey()] = entry
+}
+
+// invalidate drops the entries of url, or every entry if url is empty
+func (m *memoCache) invalidate(url string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, e := range m.entries {
+		if url == "" || e.url == url {
+			delete(m.entries, key)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package apifast
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/valyala/fasthttp"
+)
+
+// Metrics collects Prometheus metrics about the requests of the clients it is
+// attached to. It implements prometheus.Collector, so it is registered like any
+// other collector. Every request attempt is counted, retries included.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewMetrics creates the request metrics under namespace:
+//
+//	<namespace>_requests_total{host, endpoint, method, code}
+//	<namespace>_request_duration_seconds{host, endpoint, method}
+//	<namespace>_errors_total{host, endpoint, method}
+//	<namespace>_retries_total{host, endpoint, method}
+func NewMetrics(namespace string) *Metrics {
+	labels := []string{"host", "endpoint", "method"}
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Request attempts that received a response, by status code.",
+		}, append(labels, "code")),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Request attempts that failed without a response.",
+		}, labels),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Request attempts repeated by the retry policy.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of request attempts, including redirects.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.errors.Describe(ch)
+	m.retries.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.errors.Collect(ch)
+	m.retries.Collect(ch)
+	m.duration.Collect(ch)
+}
+
+// Metrics records the client's requests into m
+func (c *Client) Metrics(m *Metrics) *Client {
+	c.metrics = m
+	return c
+}
+
+// Endpoint names the request in the endpoint label of metrics. Requests
+// without a name share the empty endpoint, which keeps label cardinality
+// bounded when paths embed identifiers.
+func (b *FastBuilder) Endpoint(name string) *FastBuilder {
+	b.endpoint = name
+	return b
+}
+
+// observe records an attempt; code is ignored if the attempt failed
+func (m *Metrics) observe(ctx context.Context, b *FastBuilder, host string, elapsed time.Duration, code int, err error) {
+	if sent, ok := ctx.Value(attemptHostKey{}).(*attemptHost); ok {
+		sent.host.Store(host)
+	}
+	m.duration.WithLabelValues(host, b.endpoint, b.method).Observe(elapsed.Seconds())
+	if err != nil {
+		m.errors.WithLabelValues(host, b.endpoint, b.method).Inc()
+		return
+	}
+	m.requests.WithLabelValues(host, b.endpoint, b.method, strconv.Itoa(code)).Inc()
+}
+
+// retried records a retry of the request whose failed attempt was sent to
+// host, if known
+func (m *Metrics) retried(b *FastBuilder, host string) {
+	if host != "" {
+		m.retries.WithLabelValues(host, b.endpoint, b.method).Inc()
+		return
+	}
+	var uri fasthttp.URI
+	if err := uri.Parse(nil, []byte(b.client.resolveURL(b.url))); err != nil {
+		return
+	}
+	m.retries.WithLabelValues(string(uri.Host()), b.endpoint, b.method).Inc()
+}
+
+// attemptHostKey carries the attemptHost of the attempt being sent
+type attemptHostKey struct{}
+
+// attemptHost records the host an attempt was sent to, after balancing,
+// fallbacks and redirects
+type attemptHost struct {
+	host atomic.Value // string
+}
+
+// get returns the recorded host, "" if the attempt was not sent
+func (h *attemptHost) get() string {
+	host, _ := h.host.Load().(string)
+	return host
+}
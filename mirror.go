@@ -0,0 +1,73 @@
+package apifast
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// mirrorTimeout bounds a shadow request whose call sets no timeout
+const mirrorTimeout = 30 * time.Second
+
+// Mirror duplicates samplePercent percent of the calls under the base URL to
+// the shadow base URL url in the background, for testing a new backend with
+// production traffic. Shadow responses and errors are ignored and never delay
+// the call. Streamed and multipart bodies are not mirrored. Call Mirror after BaseURL.
+func (c *Client) Mirror(url string, samplePercent float64) *Client {
+	c.mirror = &mirror{
+		base:    strings.TrimRight(c.baseURL, "/"),
+		url:     strings.TrimRight(url, "/"),
+		percent: samplePercent,
+	}
+	return c
+}
+
+// mirror holds the shadow base URL and the share of calls sent to it
+type mirror struct {
+	base    string
+	url     string
+	percent float64
+}
+
+// shadow sends a copy of the call to the mirror, if it is sampled
+func (b *FastBuilder) shadow(ctx context.Context) {
+	m := b.client.mirror
+	if m == nil || m.base == "" || b.dryRun || b.options.bodyReader != nil || b.options.multipart != nil {
+		return
+	}
+	// Calls to other hosts would be sent to them a second time
+	if !underBase(b.client.resolveURL(b.url), m.base) {
+		return
+	}
+	if rand.Float64()*100 >= m.percent {
+		return
+	}
+
+	// The copy outlives the call, keeping only the values of its context
+	timeout := b.options.Timeout
+	if timeout <= 0 {
+		timeout = mirrorTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	ctx = withRebase(ctx, m.base, m.url)
+
+	req := fasthttp.AcquireRequest()
+	if _, _, err := b.prepare(ctx, req); err != nil {
+		fasthttp.ReleaseRequest(req)
+		cancel()
+		return
+	}
+
+	go func() {
+		defer cancel()
+		defer fasthttp.ReleaseRequest(req)
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(resp)
+
+		// Shadow cookies stay out of the client's jar
+		_ = b.client.do(ctx, req, resp)
+	}()
+}
@@ -0,0 +1,62 @@
+package apifast_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestMirror(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		wantShadow string // URL of the shadow copy, "" if the call is not mirrored
+	}{
+		{"calls under the base URL are mirrored", "/charge", "https://shadow.example.com/charge"},
+		{"the query is kept", "/charge?id=1", "https://shadow.example.com/charge?id=1"},
+		{"calls to other hosts are not mirrored", "https://other.example.com/charge", ""},
+		{"hosts sharing the prefix are not mirrored", "https://api.example.com.evil/charge", ""},
+		{"ports sharing the prefix are not mirrored", "https://api.example.com:8443/charge", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On("POST", "*").Reply(200, "ok")
+			client := apifast.NewClient().
+				BaseURL("https://api.example.com").
+				Transport(mock).
+				Mirror("https://shadow.example.com", 100)
+
+			if _, err := client.Build().Uri(tt.url).PayloadJSON(map[string]int{"amount": 1}).Post(); err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+
+			// The shadow copy is sent in the background
+			want := 1
+			if tt.wantShadow != "" {
+				want = 2
+			}
+			requests := waitForRequests(mock, want)
+			if len(requests) != want {
+				t.Fatalf("sent %d requests, want %d", len(requests), want)
+			}
+			if want == 2 && requests[1].URL != tt.wantShadow {
+				t.Errorf("shadow copy sent to %s, want %s", requests[1].URL, tt.wantShadow)
+			}
+		})
+	}
+}
+
+// waitForRequests waits briefly for mock to receive n requests, returning
+// those it received, more than n if extra ones arrive in the meantime
+func waitForRequests(mock *apifast.MockTransport, n int) []apifast.MockRequest {
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for {
+		requests := mock.Requests()
+		if len(requests) > n || time.Now().After(deadline) {
+			return requests
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,198 @@
+package apifast
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ErrNoMockRoute is returned by a MockTransport for requests no route matches
+var ErrNoMockRoute = errors.New("no mock route matches the request")
+
+// MockTransport is a Transport that answers requests with canned responses,
+// so code using apifast can be unit-tested without a server. It is safe for
+// concurrent use.
+type MockTransport struct {
+	mu       sync.Mutex
+	routes   []*MockRoute
+	requests []MockRequest
+}
+
+// MockRequest is a request received by a MockTransport
+type MockRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// MockRoute is a canned response for the requests matching a method and URL
+type MockRoute struct {
+	transport *MockTransport
+	method    string
+	url       string
+
+	status  int
+	headers []Header
+	body    []byte
+	err     error
+	delay   time.Duration
+	times   int // remaining uses, unlimited if zero
+	calls   int
+	limited bool
+}
+
+// NewMockTransport creates a MockTransport without routes
+func NewMockTransport() *MockTransport {
+	return &MockTransport{}
+}
+
+// On adds a route for method and url, answering 200 with an empty body until
+// configured otherwise. An empty method or "*" matches any method. The url
+// matches the full request URL, or only its path if it starts with "/"; a
+// trailing "*" matches any suffix. Routes are tried in the order they were added.
+func (m *MockTransport) On(method, url string) *MockRoute {
+	route := &MockRoute{transport: m, method: method, url: url, status: fasthttp.StatusOK}
+	m.mu.Lock()
+	m.routes = append(m.routes, route)
+	m.mu.Unlock()
+	return route
+}
+
+// Reply sets the status and body of the response
+func (r *MockRoute) Reply(status int, body string) *MockRoute {
+	r.status = status
+	r.body = []byte(body)
+	return r
+}
+
+// ReplyJSON sets the status and a JSON encoded body with its content type
+func (r *MockRoute) ReplyJSON(status int, v interface{}) *MockRoute {
+	body, err := stdJSON{}.Marshal(v)
+	if err != nil {
+		r.err = fmt.Errorf("failed to encode mock response: %w", err)
+		return r
+	}
+	r.status = status
+	r.body = body
+	return r.Header("Content-Type", "application/json")
+}
+
+// Header adds a response header
+func (r *MockRoute) Header(tag string, value interface{}) *MockRoute {
+	r.headers = append(r.headers, Header{Tag: tag, Value: value})
+	return r
+}
+
+// Fail makes matching requests fail with err instead of answering
+func (r *MockRoute) Fail(err error) *MockRoute {
+	r.err = err
+	return r
+}
+
+// Delay answers after d, or fails with a timeout if the request deadline
+// comes first
+func (r *MockRoute) Delay(d time.Duration) *MockRoute {
+	r.delay = d
+	return r
+}
+
+// Times limits the route to the next n matching requests
+func (r *MockRoute) Times(n int) *MockRoute {
+	r.times = n
+	r.limited = true
+	return r
+}
+
+// Calls returns the number of requests the route answered
+func (r *MockRoute) Calls() int {
+	r.transport.mu.Lock()
+	defer r.transport.mu.Unlock()
+	return r.calls
+}
+
+// Requests returns the requests received so far, in order
+func (m *MockTransport) Requests() []MockRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockRequest(nil), m.requests...)
+}
+
+// Do implements Transport
+func (m *MockTransport) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return m.DoDeadline(req, resp, time.Time{})
+}
+
+// DoDeadline implements Transport
+func (m *MockTransport) DoDeadline(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	method := string(req.Header.Method())
+	uri := req.URI()
+
+	header := make(http.Header)
+	req.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	m.mu.Lock()
+	m.requests = append(m.requests, MockRequest{
+		Method: method,
+		URL:    uri.String(),
+		Header: header,
+		Body:   append([]byte(nil), req.Body()...),
+	})
+	var route *MockRoute
+	for _, r := range m.routes {
+		if r.matches(method, uri) && (!r.limited || r.times > 0) {
+			route = r
+			r.calls++
+			if r.limited {
+				r.times--
+			}
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if route == nil {
+		return fmt.Errorf("%w: %s %s", ErrNoMockRoute, method, uri.String())
+	}
+
+	if route.delay > 0 {
+		if !deadline.IsZero() && time.Until(deadline) < route.delay {
+			time.Sleep(time.Until(deadline))
+			return fasthttp.ErrTimeout
+		}
+		time.Sleep(route.delay)
+	}
+	if route.err != nil {
+		return route.err
+	}
+
+	resp.Reset()
+	resp.SetStatusCode(route.status)
+	for _, h := range route.headers {
+		resp.Header.Set(h.Tag, fmt.Sprintf("%v", h.Value))
+	}
+	resp.SetBody(route.body)
+	return nil
+}
+
+// matches reports whether the route answers method and uri
+func (r *MockRoute) matches(method string, uri *fasthttp.URI) bool {
+	if r.method != "" && r.method != "*" && !strings.EqualFold(r.method, method) {
+		return false
+	}
+	target := uri.String()
+	if strings.HasPrefix(r.url, "/") {
+		target = string(uri.Path())
+	}
+	if prefix, ok := strings.CutSuffix(r.url, "*"); ok {
+		return strings.HasPrefix(target, prefix)
+	}
+	return target == r.url
+}
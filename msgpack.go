@@ -0,0 +1,28 @@
+package apifast
+
+import (
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// PayloadMsgpack encodes v as an application/msgpack payload
+func (b *FastBuilder) PayloadMsgpack(v interface{}) *FastBuilder {
+	payload, err := msgpack.Marshal(v)
+	if err != nil {
+		b.err = fmt.Errorf("failed to encode msgpack payload: %w", err)
+		return b
+	}
+	b.options.payload = payload
+	b.options.contentType = "application/msgpack"
+	return b
+}
+
+// ResultMsgpack sets the destination for the msgpack decoded response body,
+// whatever content type the server reports. Msgpack responses are also decoded
+// into a plain Result when their content type is msgpack.
+func (b *FastBuilder) ResultMsgpack(result interface{}) *FastBuilder {
+	b.result = result
+	b.decoder = msgpack.Unmarshal
+	return b
+}
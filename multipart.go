@@ -0,0 +1,169 @@
+package apifast
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MultipartForm describes a multipart/form-data body built from fields and files
+type MultipartForm struct {
+	parts []multipartPart
+}
+
+type multipartPart struct {
+	field    string
+	value    string    // value of a plain field
+	filename string    // set for file parts
+	path     string    // file opened when the body is streamed
+	reader   io.Reader // or an already opened reader
+	offset   int64     // start of a seekable reader, for rewinding on retry
+}
+
+// NewMultipartForm initializes an empty multipart form
+func NewMultipartForm() *MultipartForm {
+	return &MultipartForm{}
+}
+
+// Field adds a plain form field
+func (m *MultipartForm) Field(name, value string) *MultipartForm {
+	m.parts = append(m.parts, multipartPart{field: name, value: value})
+	return m
+}
+
+// File adds a file part read from path when the request is sent
+func (m *MultipartForm) File(field, path string) *MultipartForm {
+	m.parts = append(m.parts, multipartPart{field: field, filename: filepath.Base(path), path: path})
+	return m
+}
+
+// FileReader adds a file part whose content is read from r. Requests with
+// such a part can only be sent again, on retries and fallbacks, when r is an
+// io.Seeker.
+func (m *MultipartForm) FileReader(field, filename string, r io.Reader) *MultipartForm {
+	part := multipartPart{field: field, filename: filename, reader: r}
+	if seeker, ok := r.(io.Seeker); ok {
+		part.offset, _ = seeker.Seek(0, io.SeekCurrent)
+	}
+	m.parts = append(m.parts, part)
+	return m
+}
+
+// hasReaders reports whether the form streams parts from readers, which are
+// consumed by sending it
+func (m *MultipartForm) hasReaders() bool {
+	for _, p := range m.parts {
+		if p.reader != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rewind seeks the reader parts back to their start, reporting whether the
+// form can be sent again
+func (m *MultipartForm) rewind() bool {
+	for _, p := range m.parts {
+		if p.reader == nil {
+			continue
+		}
+		seeker, ok := p.reader.(io.Seeker)
+		if !ok {
+			return false
+		}
+		if _, err := seeker.Seek(p.offset, io.SeekStart); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Multipart sets a multipart/form-data payload that is streamed to the server
+func (b *FastBuilder) Multipart(form *MultipartForm) *FastBuilder {
+	b.options.multipart = form
+	return b
+}
+
+// validate fails early on missing files rather than aborting the stream mid-way
+func (m *MultipartForm) validate() error {
+	for _, p := range m.parts {
+		if p.path != "" {
+			if _, err := os.Stat(p.path); err != nil {
+				return fmt.Errorf("failed to open multipart file: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeTo streams the form into the request body with a matching
+// Content-Type. A part that fails to read aborts the body with its error
+// instead of ending it early. Releasing the request closes the pipe, which
+// stops the writer if the body is never sent.
+func (m *MultipartForm) writeTo(req *fasthttp.Request) {
+	mw := multipart.NewWriter(io.Discard)
+	req.Header.SetContentType(mw.FormDataContentType())
+	boundary := mw.Boundary()
+
+	pr, pw := io.Pipe()
+	go func() {
+		bw := bufio.NewWriter(pw)
+		mw := multipart.NewWriter(bw)
+		_ = mw.SetBoundary(boundary)
+		for _, p := range m.parts {
+			if err := p.write(mw); err != nil {
+				pw.CloseWithError(fmt.Errorf("failed to write multipart part %q: %w", p.field, err))
+				return
+			}
+		}
+		err := mw.Close()
+		if err == nil {
+			err = bw.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	req.SetBodyStream(pr, -1)
+}
+
+// write emits a single part into the multipart writer
+func (p multipartPart) write(mw *multipart.Writer) error {
+	if p.filename == "" {
+		return mw.WriteField(p.field, p.value)
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(p.field), quoteEscaper.Replace(p.filename)))
+	contentType := mime.TypeByExtension(filepath.Ext(p.filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	r := p.reader
+	if r == nil {
+		f, err := os.Open(p.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	_, err = io.Copy(part, r)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
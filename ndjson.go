@@ -0,0 +1,56 @@
+package apifast
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ResultStream decodes a newline-delimited JSON (NDJSON / JSON Lines) response
+// record by record as it arrives, calling fn with every record instead of
+// buffering the whole body. Returning an error from fn aborts the stream.
+func ResultStream[T any](b *FastBuilder, fn func(v T) error) *FastBuilder {
+	return b.ResultWriter(&ndjsonWriter{decode: func(line []byte) error {
+		var v T
+		if err := b.client.json().Unmarshal(line, &v); err != nil {
+			return fmt.Errorf("failed to decode NDJSON record: %w", err)
+		}
+		return fn(v)
+	}})
+}
+
+// ndjsonWriter splits the streamed body into lines and decodes each record
+type ndjsonWriter struct {
+	decode func(line []byte) error
+	buf    []byte
+}
+
+func (w *ndjsonWriter) Write(chunk []byte) (int, error) {
+	w.buf = append(w.buf, chunk...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := w.record(w.buf[:i]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(chunk), nil
+}
+
+// flushBody decodes a final record that is not newline-terminated
+func (w *ndjsonWriter) flushBody() error {
+	line := w.buf
+	w.buf = nil
+	return w.record(line)
+}
+
+// record decodes a single line, skipping blank ones
+func (w *ndjsonWriter) record(line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	return w.decode(line)
+}
@@ -0,0 +1,134 @@
+package apifast
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenSource supplies bearer tokens, fetching or refreshing them as needed
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// ClientCredentials is a TokenSource implementing the OAuth2 client
+// credentials grant. Tokens are cached and refreshed shortly before expiry.
+type ClientCredentials struct {
+	TokenURL       string        // Token endpoint
+	ClientID       string        // Client identifier
+	ClientSecret   string        // Client secret, sent with Basic authentication; public clients without one send ClientID in the form
+	Scopes         []string      // Requested scopes
+	EndpointParams url.Values    // Additional parameters sent to the token endpoint
+	RefreshBefore  time.Duration // How long before expiry a token is refreshed, 30s if zero, at most half its lifetime
+	Client         *Client       // Client used to call the token endpoint, the default client if nil
+
+	mu       sync.Mutex
+	token    string
+	expiry   time.Time     // zero when the token does not expire
+	fetching chan struct{} // closed when the fetch in flight ends, nil if none
+}
+
+// tokenResponse is the token endpoint reply defined by RFC 6749
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Token returns the cached access token, fetching a new one when it is
+// missing or about to expire. Concurrent callers wait for a single fetch.
+func (cc *ClientCredentials) Token(ctx context.Context) (string, error) {
+	for {
+		cc.mu.Lock()
+		if cc.token != "" && (cc.expiry.IsZero() || time.Now().Before(cc.expiry)) {
+			token := cc.token
+			cc.mu.Unlock()
+			return token, nil
+		}
+		if fetching := cc.fetching; fetching != nil {
+			cc.mu.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return "", fmt.Errorf("failed to obtain access token: %w", ctx.Err())
+			}
+		}
+		fetching := make(chan struct{})
+		cc.fetching = fetching
+		cc.mu.Unlock()
+
+		token, expiry, err := cc.fetch(ctx)
+		cc.mu.Lock()
+		cc.fetching = nil
+		if err == nil {
+			cc.token, cc.expiry = token, expiry
+		}
+		cc.mu.Unlock()
+		close(fetching)
+		return token, err
+	}
+}
+
+// fetch requests a new token from the token endpoint, returning it with the
+// time it should be refreshed at
+func (cc *ClientCredentials) fetch(ctx context.Context) (string, time.Time, error) {
+	client := cc.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	form := url.Values{}
+	for k, v := range cc.EndpointParams {
+		form[k] = v
+	}
+	form.Set("grant_type", "client_credentials")
+	if len(cc.Scopes) > 0 {
+		form.Set("scope", strings.Join(cc.Scopes, " "))
+	}
+	// Public clients identify themselves in the form; either way the client
+	// default auth, which may be this very token source, is never used
+	var auth Auth
+	if cc.ClientSecret != "" {
+		auth = Auth{Username: url.QueryEscape(cc.ClientID), Password: url.QueryEscape(cc.ClientSecret)}
+	} else if cc.ClientID != "" {
+		form.Set("client_id", cc.ClientID)
+	}
+
+	var tok tokenResponse
+	b := client.Build().
+		WithContext(ctx).
+		Uri(cc.TokenURL).
+		Auth(auth).
+		PayloadForm(form).
+		Result(&tok)
+	b.ownAuth = true
+	if _, err := b.Post(); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to obtain access token: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("failed to obtain access token: empty access_token in response")
+	}
+
+	var expiry time.Time
+	if tok.ExpiresIn > 0 {
+		refreshBefore := cc.RefreshBefore
+		if refreshBefore == 0 {
+			refreshBefore = 30 * time.Second
+		}
+		// Short-lived tokens are still used for half their lifetime
+		lifetime := time.Duration(tok.ExpiresIn) * time.Second
+		expiry = time.Now().Add(lifetime - min(refreshBefore, lifetime/2))
+	}
+	return tok.AccessToken, expiry, nil
+}
+
+// Invalidate drops the cached token so the next request fetches a new one
+func (cc *ClientCredentials) Invalidate() {
+	cc.mu.Lock()
+	cc.token = ""
+	cc.mu.Unlock()
+}
@@ -0,0 +1,112 @@
+package apifast_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestClientCredentialsAuth(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientSecret string
+		wantAuth     string // Authorization header of the token request
+		wantClientID string // client_id form field of the token request
+	}{
+		{
+			name:         "confidential client authenticates with Basic",
+			clientSecret: "s3cret",
+			wantAuth:     "Basic " + base64.StdEncoding.EncodeToString([]byte("my-service:s3cret")),
+		},
+		{
+			name:         "public client sends its ID in the form",
+			wantClientID: "my-service",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On("POST", "/oauth/token").ReplyJSON(200, map[string]interface{}{"access_token": "tok", "expires_in": 3600})
+			mock.On("GET", "/orders").Reply(200, "[]")
+			// The token endpoint is called through the client the tokens are for
+			client := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock)
+			tokens := &apifast.ClientCredentials{
+				TokenURL:     "https://api.example.com/oauth/token",
+				ClientID:     "my-service",
+				ClientSecret: tt.clientSecret,
+				Client:       client,
+			}
+			client.Auth(apifast.Auth{TokenSource: tokens})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := client.Build().WithContext(ctx).Uri("/orders").Get(); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			requests := mock.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("sent %d requests, want the token request and the call", len(requests))
+			}
+			token, call := requests[0], requests[1]
+			if got := token.Header.Get("Authorization"); got != tt.wantAuth {
+				t.Errorf("token request Authorization = %q, want %q", got, tt.wantAuth)
+			}
+			form, err := url.ParseQuery(string(token.Body))
+			if err != nil {
+				t.Fatalf("invalid token request form %q: %v", token.Body, err)
+			}
+			if got := form.Get("client_id"); got != tt.wantClientID {
+				t.Errorf("token request client_id = %q, want %q", got, tt.wantClientID)
+			}
+			if got := form.Get("grant_type"); got != "client_credentials" {
+				t.Errorf("token request grant_type = %q, want client_credentials", got)
+			}
+			if got := call.Header.Get("Authorization"); got != "Bearer tok" {
+				t.Errorf("call Authorization = %q, want %q", got, "Bearer tok")
+			}
+		})
+	}
+}
+
+func TestClientCredentialsSingleFetch(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("POST", "/oauth/token").
+		ReplyJSON(200, map[string]interface{}{"access_token": "tok", "expires_in": 3600}).
+		Delay(50 * time.Millisecond)
+	tokens := &apifast.ClientCredentials{
+		TokenURL:     "https://auth.example.com/oauth/token",
+		ClientID:     "my-service",
+		ClientSecret: "s3cret",
+		Client:       apifast.NewClient().Transport(mock),
+	}
+
+	// A caller giving up does not wait for the fetch in flight
+	go tokens.Token(context.Background())
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tokens.Token(ctx); err == nil {
+		t.Error("Token() with a canceled context succeeded while a fetch was in flight")
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := tokens.Token(context.Background()); err != nil || token != "tok" {
+				t.Errorf("Token() = %q, %v, want tok", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := len(mock.Requests()); got != 1 {
+		t.Errorf("sent %d token requests, want 1", got)
+	}
+}
@@ -0,0 +1,187 @@
+package apifast
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrStopPagination can be returned by a page callback to stop paginating without an error
+var ErrStopPagination = errors.New("stop pagination")
+
+// Pagination selects how the next page of a paginated endpoint is located
+type Pagination struct {
+	CursorField string // JSON field holding the next cursor, dot-separated for nested fields; Link headers are followed if empty
+	CursorParam string // Query parameter receiving the cursor on the next request, required with CursorField
+	MaxPages    int    // Upper bound on the number of pages fetched, unbounded if zero
+}
+
+// Paginate sends the request and keeps fetching pages until there is no next
+// page, calling fn with every page. The next page is found through the RFC 5988
+// Link header (rel="next") or, when CursorField is set, a cursor in the JSON body.
+// If a Result is set it is decoded anew for every page before fn is called.
+func (b *FastBuilder) Paginate(p Pagination, fn func(page *Response) error) error {
+	if p.CursorField != "" && p.CursorParam == "" {
+		return errors.New("pagination: CursorParam is required with CursorField")
+	}
+	if b.method == "" {
+		b.method = "GET"
+	}
+
+	for pages := 0; p.MaxPages <= 0 || pages < p.MaxPages; pages++ {
+		page, err := b.makeRequest()
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			if errors.Is(err, ErrStopPagination) {
+				return nil
+			}
+			return err
+		}
+
+		next, err := b.nextPage(p, page)
+		if err != nil || next == "" {
+			return err
+		}
+		b.url = next
+	}
+	return nil
+}
+
+// nextPage returns the URL of the page following page, or "" on the last page
+func (b *FastBuilder) nextPage(p Pagination, page *Response) (string, error) {
+	current, err := url.Parse(b.client.resolveURL(b.url))
+	if err != nil {
+		return "", err
+	}
+
+	if p.CursorField == "" {
+		for _, link := range page.Headers.Values("Link") {
+			if next := parseNextLink(link); next != "" {
+				ref, err := url.Parse(next)
+				if err != nil {
+					return "", fmt.Errorf("invalid next link: %w", err)
+				}
+				return current.ResolveReference(ref).String(), nil
+			}
+		}
+		return "", nil
+	}
+
+	cursor, err := jsonCursor(page, p.CursorField)
+	if err != nil || cursor == "" {
+		return "", err
+	}
+	query := current.Query()
+	query.Set(p.CursorParam, cursor)
+	current.RawQuery = query.Encode()
+	return current.String(), nil
+}
+
+// parseNextLink extracts the rel="next" target of a Link header value. Targets
+// are read up to their closing bracket and quoted parameters up to their
+// closing quote, so commas and semicolons inside them do not split links.
+func parseNextLink(header string) string {
+	s := header
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			return ""
+		}
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			return ""
+		}
+		target := s[start+1 : start+end]
+		s = s[start+end+1:]
+
+		// Parameters run until the comma ending the link
+		var params []string
+		for {
+			s = strings.TrimLeft(s, " \t")
+			if !strings.HasPrefix(s, ";") {
+				break
+			}
+			var param string
+			param, s = readLinkParam(s[1:])
+			params = append(params, param)
+		}
+		for _, param := range params {
+			key, value, _ := strings.Cut(param, "=")
+			if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(value) {
+				if strings.EqualFold(rel, "next") {
+					return strings.TrimSpace(target)
+				}
+			}
+		}
+	}
+}
+
+// readLinkParam reads a link parameter from s, unquoting its value, and
+// returns it with the rest of s
+func readLinkParam(s string) (string, string) {
+	var param strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(s):
+			i++
+			param.WriteByte(s[i])
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == ';' || c == ','):
+			return param.String(), s[i:]
+		default:
+			param.WriteByte(c)
+		}
+	}
+	return param.String(), ""
+}
+
+// jsonCursor reads the cursor at the dot-separated path of a JSON response
+// body with the client's codec. Numbers are returned as written, so large IDs
+// keep every digit instead of turning into floats.
+func jsonCursor(resp *Response, path string) (string, error) {
+	codec := resp.codec
+	if codec == nil {
+		codec = stdJSON{}
+	}
+	value := json.RawMessage(resp.Body)
+	if !json.Valid(value) {
+		return "", errors.New("failed to read pagination cursor: invalid JSON body")
+	}
+	for _, key := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if bytes.HasPrefix(bytes.TrimSpace(value), []byte("{")) {
+			if err := codec.Unmarshal(value, &object); err != nil {
+				return "", fmt.Errorf("failed to read pagination cursor: %w", err)
+			}
+		}
+		value = object[key]
+	}
+
+	value = bytes.TrimSpace(value)
+	switch {
+	case len(value) == 0:
+		return "", nil
+	case value[0] == '"':
+		var cursor string
+		if err := codec.Unmarshal(value, &cursor); err != nil {
+			return "", fmt.Errorf("failed to read pagination cursor: %w", err)
+		}
+		return cursor, nil
+	case value[0] == '-' || value[0] >= '0' && value[0] <= '9':
+		return string(value), nil
+	default:
+		// null, booleans, objects and arrays are no cursor
+		return "", nil
+	}
+}
@@ -0,0 +1,113 @@
+package apifast_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestPaginateCursor(t *testing.T) {
+	tests := []struct {
+		name       string
+		field      string
+		body       string // body of the first page
+		wantCursor string // cursor sent for the second page, "" if there is none
+	}{
+		{"string cursor", "next", `{"next":"abc"}`, "abc"},
+		{"small number", "next", `{"next":42}`, "42"},
+		{"number over a million", "next", `{"next":1234567}`, "1234567"},
+		{"64-bit ID beyond float precision", "next", `{"next":9007199254740993}`, "9007199254740993"},
+		{"decimal number", "next", `{"next":1.5}`, "1.5"},
+		{"nested field", "meta.next", `{"meta": {"next": 7654321}}`, "7654321"},
+		{"null ends pagination", "next", `{"next":null}`, ""},
+		{"missing field ends pagination", "meta.next", `{"meta":"none"}`, ""},
+		{"boolean ends pagination", "next", `{"next":false}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On("GET", "/items").Reply(200, tt.body).Times(1)
+			mock.On("GET", "/items").Reply(200, `{}`)
+			client := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock)
+
+			err := client.Build().Uri("/items").Paginate(apifast.Pagination{
+				CursorField: tt.field,
+				CursorParam: "cursor",
+			}, func(*apifast.Response) error { return nil })
+			if err != nil {
+				t.Fatalf("Paginate() error = %v", err)
+			}
+
+			requests := mock.Requests()
+			if tt.wantCursor == "" {
+				if len(requests) != 1 {
+					t.Errorf("fetched %d pages, want 1", len(requests))
+				}
+				return
+			}
+			if len(requests) != 2 {
+				t.Fatalf("fetched %d pages, want 2", len(requests))
+			}
+			if got := queryParam(t, requests[1].URL, "cursor"); got != tt.wantCursor {
+				t.Errorf("second page requested with cursor %q, want %q", got, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestPaginateCursorUsesClientCodec(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("GET", "/items").Reply(200, `{"next":"abc"}`).Times(1)
+	mock.On("GET", "/items").Reply(200, `{}`)
+	codec := &countingCodec{}
+	client := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock).JSONCodec(codec)
+
+	err := client.Build().Uri("/items").Paginate(apifast.Pagination{
+		CursorField: "next",
+		CursorParam: "cursor",
+	}, func(*apifast.Response) error { return nil })
+	if err != nil {
+		t.Fatalf("Paginate() error = %v", err)
+	}
+	if codec.unmarshals == 0 {
+		t.Error("cursor was read without the client's codec")
+	}
+}
+
+func TestLongPollCursorKeepsLargeNumbers(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("GET", "/events").Reply(200, `{"index":12345678}`)
+	client := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock)
+
+	polls := 0
+	err := client.Build().Uri("/events").LongPoll(apifast.LongPollSettings{
+		WaitParam:   "wait",
+		CursorField: "index",
+		CursorParam: "index",
+	}, func(*apifast.Response) error {
+		if polls++; polls == 2 {
+			return apifast.ErrStopPolling
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("LongPoll() error = %v", err)
+	}
+	requests := mock.Requests()
+	if got := queryParam(t, requests[len(requests)-1].URL, "index"); got != "12345678" {
+		t.Errorf("next poll requested with index %q, want 12345678", got)
+	}
+}
+
+// countingCodec is encoding/json counting the bodies it decodes
+type countingCodec struct {
+	unmarshals int
+}
+
+func (c *countingCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (c *countingCodec) Unmarshal(data []byte, v interface{}) error {
+	c.unmarshals++
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,59 @@
+package apifast
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// PayloadFile streams the file at path as the request payload with its
+// Content-Length, instead of reading it into memory. The Content-Type is
+// derived from the file extension, or sniffed from its first bytes, unless
+// set otherwise. The file is opened when the request is sent and closed
+// afterwards; retries rewind it.
+func (b *FastBuilder) PayloadFile(path string) *FastBuilder {
+	b.payloadFile = path
+	return b
+}
+
+// openPayloadFile opens the file set by PayloadFile as the request body,
+// returning the function that closes it and restores the builder, so the
+// file is opened anew by every send
+func (b *FastBuilder) openPayloadFile() (func(), error) {
+	f, err := os.Open(b.payloadFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open payload file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open payload file: %w", err)
+	}
+
+	saved := b.options
+	if b.options.contentType == "" {
+		contentType := mime.TypeByExtension(filepath.Ext(b.payloadFile))
+		if contentType == "" {
+			head := make([]byte, 512)
+			n, _ := io.ReadFull(f, head)
+			contentType = http.DetectContentType(head[:n])
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("failed to open payload file: %w", err)
+			}
+		}
+		b.options.contentType = contentType
+	}
+
+	b.options.bodyReader = f
+	b.options.bodySize = int(info.Size())
+	b.options.bodyOffset = 0
+	return func() {
+		f.Close()
+		b.options.bodyReader, b.options.bodySize = saved.bodyReader, saved.bodySize
+		b.options.bodyOffset, b.options.contentType = saved.bodyOffset, saved.contentType
+	}, nil
+}
@@ -0,0 +1,70 @@
+package apifast_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestPayloadFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"order.json": `{"id":1}`,
+		"notes.txt":  "hello",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		file            string
+		wantContentType string
+	}{
+		{"type from the extension", "order.json", "application/json"},
+		{"type of another file sent with the same builder", "notes.txt", "text/plain; charset=utf-8"},
+	}
+	mock := apifast.NewMockTransport()
+	mock.On("POST", "/upload").Reply(200, "ok")
+	b := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock).Build().Uri("/upload")
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			b.PayloadFile(path)
+
+			prepared, err := b.DryRun(true).Post()
+			if err != nil {
+				t.Fatalf("dry run error = %v", err)
+			}
+			if got := string(prepared.Request.Body); got != files[tt.file] {
+				t.Errorf("dry run body = %q, want %q", got, files[tt.file])
+			}
+			if got := prepared.Request.Headers.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("dry run Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+
+			curl := b.AsCurl()
+			for _, want := range []string{"--data-binary @" + path, "Content-Type: " + tt.wantContentType} {
+				if !strings.Contains(curl, want) {
+					t.Errorf("AsCurl() = %s, want it to contain %q", curl, want)
+				}
+			}
+
+			if _, err := b.DryRun(false).Post(); err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			sent := mock.Requests()[i]
+			if string(sent.Body) != files[tt.file] {
+				t.Errorf("sent body %q, want %q", sent.Body, files[tt.file])
+			}
+			if got := sent.Header.Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("sent Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+		})
+	}
+}
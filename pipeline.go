@@ -0,0 +1,20 @@
+package apifast
+
+import "time"
+
+// PipelineSettings configures pipelined connections to the base URL host
+type PipelineSettings struct {
+	MaxConns           int           // Pipelined connections to the host, 1 if zero
+	MaxPendingRequests int           // Requests queued per connection before fasthttp.ErrPipelineOverflow, 1024 if zero
+	MaxBatchDelay      time.Duration // How long queued requests wait to be written together, no delay if zero
+}
+
+// Pipeline sends requests to the host of the base URL over pipelined
+// connections: requests are written back to back without waiting for the
+// previous response, in batches of everything queued within MaxBatchDelay.
+// It maximizes throughput for internal traffic to servers that support
+// pipelining. Requests to other hosts and streamed responses are unaffected.
+func (c *Client) Pipeline(settings PipelineSettings) *Client {
+	c.pipeline = &settings
+	return c
+}
@@ -0,0 +1,35 @@
+package apifast
+
+import (
+	"context"
+	"time"
+)
+
+// PollUntil sends the request every interval until done reports true for a
+// response, for async job status endpoints, and returns that response. It
+// stops early when done or a request fails, or when the context set by
+// WithContext expires; Timeout bounds every single poll. If a Result is set
+// it is decoded anew for every response before done is called.
+func (b *FastBuilder) PollUntil(interval time.Duration, done func(resp *Response) (bool, error)) (*Response, error) {
+	if b.method == "" {
+		b.method = "GET"
+	}
+	ctx := b.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		resp, err := b.makeRequest()
+		if err != nil {
+			return resp, err
+		}
+		finished, err := done(resp)
+		if finished || err != nil {
+			return resp, err
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return resp, b.wrapError(err)
+		}
+	}
+}
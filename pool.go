@@ -0,0 +1,32 @@
+package apifast
+
+import "time"
+
+// MaxConnsPerHost limits the number of open connections to each host,
+// fasthttp.DefaultMaxConnsPerHost (512) if zero. Requests beyond the limit
+// fail at once, unless MaxConnWaitTimeout lets them wait for a free connection.
+func (c *Client) MaxConnsPerHost(n int) *Client {
+	c.hc.MaxConnsPerHost = n
+	return c
+}
+
+// MaxIdleConnDuration closes keep-alive connections that stay idle for longer
+// than d, fasthttp.DefaultMaxIdleConnDuration (10s) if zero
+func (c *Client) MaxIdleConnDuration(d time.Duration) *Client {
+	c.hc.MaxIdleConnDuration = d
+	return c
+}
+
+// MaxConnWaitTimeout lets requests wait up to d for a free connection once a
+// host reached MaxConnsPerHost, instead of failing with fasthttp.ErrNoFreeConns
+func (c *Client) MaxConnWaitTimeout(d time.Duration) *Client {
+	c.hc.MaxConnWaitTimeout = d
+	return c
+}
+
+// ReadBufferSize sets the per-connection buffer used to read responses, which
+// also limits the size of response headers; 4096 bytes if zero
+func (c *Client) ReadBufferSize(n int) *Client {
+	c.hc.ReadBufferSize = n
+	return c
+}
@@ -0,0 +1,66 @@
+package apifast
+
+// Common request priorities; any int works, higher values are served first
+const (
+	PriorityLow    = -10 // Background and batch traffic
+	PriorityNormal = 0   // The default
+	PriorityHigh   = 10  // Interactive, user-facing calls
+)
+
+// Priority sets the rank of the request in the queues of the client's rate
+// and concurrency limits. Queued attempts with a higher priority are admitted
+// first, those of equal priority in arrival order.
+func (b *FastBuilder) Priority(priority int) *FastBuilder {
+	b.priority = priority
+	return b
+}
+
+// waiter is an attempt queued behind a limit
+type waiter struct {
+	priority int
+	ready    chan struct{} // signaled when the waiter is admitted or must check again
+}
+
+func newWaiter(priority int) *waiter {
+	return &waiter{priority: priority, ready: make(chan struct{}, 1)}
+}
+
+// signal wakes the waiter without blocking
+func (w *waiter) signal() {
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+// waitQueue orders waiters by descending priority, then by arrival
+type waitQueue []*waiter
+
+// push queues w behind the waiters of the same or a higher priority
+func (q *waitQueue) push(w *waiter) {
+	i := len(*q)
+	for i > 0 && (*q)[i-1].priority < w.priority {
+		i--
+	}
+	*q = append(*q, nil)
+	copy((*q)[i+1:], (*q)[i:])
+	(*q)[i] = w
+}
+
+// pop removes and returns the first waiter
+func (q *waitQueue) pop() *waiter {
+	w := (*q)[0]
+	*q = (*q)[1:]
+	return w
+}
+
+// remove drops w from the queue, reporting whether it was queued
+func (q *waitQueue) remove(w *waiter) bool {
+	for i, queued := range *q {
+		if queued == w {
+			*q = append((*q)[:i], (*q)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package apifast
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// PayloadProto encodes m as an application/x-protobuf payload
+func (b *FastBuilder) PayloadProto(m proto.Message) *FastBuilder {
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		b.err = fmt.Errorf("failed to encode protobuf payload: %w", err)
+		return b
+	}
+	b.options.payload = payload
+	b.options.contentType = "application/x-protobuf"
+	return b
+}
+
+// ResultProto sets the destination for the protobuf decoded response body,
+// whatever content type the server reports. Protobuf responses are also
+// decoded into a plain Result holding a proto.Message.
+func (b *FastBuilder) ResultProto(m proto.Message) *FastBuilder {
+	b.result = m
+	b.decoder = unmarshalProto
+	return b
+}
+
+// unmarshalProto decodes a protobuf message into dest, which must be a
+// proto.Message
+func unmarshalProto(data []byte, dest interface{}) error {
+	m, ok := dest.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot decode protobuf into %T: not a proto.Message", dest)
+	}
+	return proto.Unmarshal(data, m)
+}
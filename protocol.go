@@ -0,0 +1,208 @@
+package apifast
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Protocol is the HTTP version a client speaks
+type Protocol int
+
+const (
+	HTTP1 Protocol = iota // HTTP/1.1 over fasthttp, the default
+	HTTP2                 // HTTP/2 over net/http for https URLs, falling back to HTTP/1.1
+	HTTP3                 // HTTP/3 over QUIC for https URLs, experimental
+)
+
+// Protocol selects the HTTP version of the client's requests. HTTP2 sends
+// requests through a net/http transport with HTTP/2 enabled, for APIs that
+// require h2. The builder API, hooks, retries, cookies and dialing options
+// work unchanged; the fasthttp pool settings and pipelining do not apply.
+// HTTP3 sends requests over QUIC with quic-go; it only supports https URLs,
+// and dialing and proxy options do not apply to its UDP connections.
+func (c *Client) Protocol(p Protocol) *Client {
+	c.protocol = p
+	return c
+}
+
+// configureProtocol sets up the transport of protocols not served by fasthttp
+func (c *Client) configureProtocol() error {
+	var t http.RoundTripper
+	switch c.protocol {
+	case HTTP2:
+		t = c.httpTransport()
+	case HTTP3:
+		t = c.http3Transport()
+	default:
+		return nil
+	}
+	c.pool.std = newStdTransport(t, c.hc.MaxResponseBodySize)
+	return nil
+}
+
+// httpTransport builds a net/http transport from the client settings
+func (c *Client) httpTransport() *http.Transport {
+	t := &http.Transport{
+		ForceAttemptHTTP2:     true,
+		DisableCompression:    true, // Accept-Encoding is negotiated by the client
+		TLSClientConfig:       c.hc.TLSConfig,
+		TLSHandshakeTimeout:   c.tlsHandshakeTimeout,
+		ResponseHeaderTimeout: c.hc.ReadTimeout,
+		IdleConnTimeout:       c.hc.MaxIdleConnDuration,
+		MaxConnsPerHost:       c.hc.MaxConnsPerHost,
+	}
+	if c.expect != nil {
+		t.ExpectContinueTimeout = expectContinueTimeout
+	}
+	if t.TLSClientConfig != nil {
+		t.TLSClientConfig = t.TLSClientConfig.Clone()
+	}
+	if c.customDial() {
+		t.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			var timeout time.Duration
+			if deadline, ok := ctx.Deadline(); ok {
+				timeout = time.Until(deadline)
+			}
+			return c.dial(addr, timeout)
+		}
+	}
+
+	if c.proxy != nil {
+		cfg := &c.proxy.Config
+		if c.proxyFromEnv {
+			cfg = httpproxy.FromEnvironment()
+		}
+		proxyFunc := cfg.ProxyFunc()
+		t.Proxy = func(req *http.Request) (*url.URL, error) { return proxyFunc(req.URL) }
+	}
+	return t
+}
+
+// http3Transport builds a QUIC transport from the client settings
+func (c *Client) http3Transport() *http3.Transport {
+	t := &http3.Transport{
+		DisableCompression: true,
+		QUICConfig:         &quic.Config{HandshakeIdleTimeout: c.tlsHandshakeTimeout},
+	}
+	if c.hc.TLSConfig != nil {
+		t.TLSClientConfig = c.hc.TLSConfig.Clone()
+	}
+	return t
+}
+
+// stdTransport bridges fasthttp requests and responses to a net/http client
+type stdTransport struct {
+	client      *http.Client
+	maxBodySize int
+}
+
+// newStdTransport bridges to rt, failing responses with bodies over maxBodySize if positive
+func newStdTransport(rt http.RoundTripper, maxBodySize int) *stdTransport {
+	return &stdTransport{client: &http.Client{
+		Transport: rt,
+		// Redirects are followed by the client, like with fasthttp
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}, maxBodySize: maxBodySize}
+}
+
+func (t *stdTransport) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	return t.do(ctx, req, resp, cancel)
+}
+
+func (t *stdTransport) DoDeadline(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := time.AfterFunc(time.Until(deadline), cancel)
+
+	// A streamed body is read past the call, at the caller's pace
+	err := t.do(ctx, req, resp, cancel)
+	timer.Stop()
+	return err
+}
+
+// do sends req through net/http and copies the outcome into resp; release
+// ends the exchange once the response body is consumed
+func (t *stdTransport) do(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, release func()) error {
+	var body io.Reader = bytes.NewReader(req.Body())
+	if bodyStream := req.BodyStream(); bodyStream != nil {
+		body = bodyStream
+	}
+	hreq, err := http.NewRequestWithContext(ctx, string(req.Header.Method()), req.URI().String(), body)
+	if err != nil {
+		return err
+	}
+	if req.BodyStream() != nil {
+		hreq.ContentLength = int64(req.Header.ContentLength())
+		if hreq.ContentLength < 0 {
+			hreq.ContentLength = -1
+		}
+	}
+	req.Header.VisitAll(func(key, value []byte) {
+		switch k := string(key); k {
+		case fasthttp.HeaderHost:
+			hreq.Host = string(value)
+		case fasthttp.HeaderContentLength, fasthttp.HeaderConnection, fasthttp.HeaderTransferEncoding:
+		default:
+			hreq.Header.Add(k, string(value))
+		}
+	})
+
+	hresp, err := t.client.Do(hreq)
+	if err != nil {
+		release()
+		return err
+	}
+
+	resp.SetStatusCode(hresp.StatusCode)
+	for key, values := range hresp.Header {
+		for _, value := range values {
+			resp.Header.Add(key, value)
+		}
+	}
+	if resp.StreamBody && !resp.SkipBody {
+		resp.SetBodyStream(&releaseBody{ReadCloser: hresp.Body, release: release}, int(hresp.ContentLength))
+		return nil
+	}
+	defer release()
+	defer hresp.Body.Close()
+	if resp.SkipBody {
+		return nil
+	}
+
+	r := io.Reader(hresp.Body)
+	if t.maxBodySize > 0 {
+		r = io.LimitReader(r, int64(t.maxBodySize)+1)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if t.maxBodySize > 0 && len(b) > t.maxBodySize {
+		return fasthttp.ErrBodyTooLarge
+	}
+	resp.SetBody(b)
+	return nil
+}
+
+// releaseBody ends the exchange of a streamed response once its body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,37 @@
+package apifast
+
+import (
+	"github.com/valyala/fasthttp/fasthttpproxy"
+)
+
+// Proxy routes requests through the proxy at proxyURL. Supported schemes are
+// http (using CONNECT tunnels for https targets), socks5 and socks5h.
+func (c *Client) Proxy(proxyURL string) *Client {
+	d := c.proxyDialer()
+	d.Config.HTTPProxy = proxyURL
+	d.Config.HTTPSProxy = proxyURL
+	c.proxyFromEnv = false
+	return c
+}
+
+// NoProxy excludes hosts from proxying, using the NO_PROXY syntax
+func (c *Client) NoProxy(hosts string) *Client {
+	c.proxyDialer().Config.NoProxy = hosts
+	return c
+}
+
+// ProxyFromEnvironment routes requests according to the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables
+func (c *Client) ProxyFromEnvironment() *Client {
+	c.proxyDialer()
+	c.proxyFromEnv = true
+	return c
+}
+
+// proxyDialer returns the client's proxy dialer, creating it on first use
+func (c *Client) proxyDialer() *fasthttpproxy.Dialer {
+	if c.proxy == nil {
+		c.proxy = &fasthttpproxy.Dialer{}
+	}
+	return c.proxy
+}
@@ -0,0 +1,155 @@
+package apifast
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter holds the per-host token buckets of a client
+type rateLimiter struct {
+	rps      float64
+	burst    int
+	failFast bool
+	buckets  sync.Map // host -> *tokenBucket
+}
+
+// RateLimit throttles outgoing requests to rps per host, allowing bursts of up
+// to burst requests. Requests over the limit wait for a token unless
+// RateLimitFailFast is set. A rate that is not positive and finite removes the
+// limit.
+func (c *Client) RateLimit(rps float64, burst int) *Client {
+	if !(rps > 0) || math.IsInf(rps, 1) {
+		c.rateLimit = nil
+		return c
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	c.rateLimit = &rateLimiter{rps: rps, burst: burst}
+	return c
+}
+
+// RateLimitFailFast makes requests over the rate limit fail with ErrRateLimited instead of waiting
+func (c *Client) RateLimitFailFast() *Client {
+	if c.rateLimit != nil {
+		c.rateLimit.failFast = true
+	}
+	return c
+}
+
+// wait blocks until the rate limit of host admits another request, queueing by priority
+func (l *rateLimiter) wait(ctx context.Context, host string, priority int) error {
+	bucket, _ := l.buckets.LoadOrStore(host, newTokenBucket(l.rps, l.burst))
+	tb := bucket.(*tokenBucket)
+
+	if l.failFast {
+		if !tb.allow(time.Now()) {
+			return &permanentError{ErrRateLimited}
+		}
+		return nil
+	}
+
+	return tb.take(ctx, priority)
+}
+
+// tokenBucket is a token bucket refilled continuously at rate tokens per second
+type tokenBucket struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	waiters waitQueue
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// refill adds the tokens accumulated since the last update
+func (tb *tokenBucket) refill(now time.Time) {
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+}
+
+// allow takes a token if one is available
+func (tb *tokenBucket) allow(now time.Time) bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(now)
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
+
+// take waits until ctx is done for a token. Waiters are served by priority:
+// only the first one sleeps until the next token, the others until they lead.
+func (tb *tokenBucket) take(ctx context.Context, priority int) error {
+	tb.mu.Lock()
+	tb.refill(time.Now())
+	if len(tb.waiters) == 0 && tb.tokens >= 1 {
+		tb.tokens--
+		tb.mu.Unlock()
+		return nil
+	}
+	w := newWaiter(priority)
+	var leader *waiter
+	if len(tb.waiters) > 0 {
+		leader = tb.waiters[0]
+	}
+	tb.waiters.push(w)
+	// A waiter jumping ahead takes over the wait for the next token
+	if leader != nil && tb.waiters[0] == w {
+		leader.signal()
+	}
+	tb.mu.Unlock()
+
+	for {
+		tb.mu.Lock()
+		tb.refill(time.Now())
+		leading := tb.waiters[0] == w
+		if leading && tb.tokens >= 1 {
+			tb.tokens--
+			tb.waiters.pop()
+			if len(tb.waiters) > 0 {
+				tb.waiters[0].signal()
+			}
+			tb.mu.Unlock()
+			return nil
+		}
+		var timer *time.Timer
+		var next <-chan time.Time
+		if leading {
+			timer = time.NewTimer(time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second)))
+			next = timer.C
+		}
+		tb.mu.Unlock()
+
+		select {
+		case <-w.ready:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-next:
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			tb.mu.Lock()
+			leading = tb.waiters[0] == w
+			tb.waiters.remove(w)
+			if leading && len(tb.waiters) > 0 {
+				tb.waiters[0].signal()
+			}
+			tb.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package apifast_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		rps      float64
+		burst    int
+		wantSent int // of 10 requests sent at once
+	}{
+		{"burst is admitted at once", 1, 3, 3},
+		{"burst below 1 admits one request", 1, 0, 1},
+		{"zero rate disables the limit", 0, 5, 10},
+		{"negative rate disables the limit", -1, 5, 10},
+		{"NaN rate disables the limit", math.NaN(), 5, 10},
+		{"infinite rate disables the limit", math.Inf(1), 5, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On("GET", "/items").Reply(200, "ok")
+			client := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock).
+				RateLimit(tt.rps, tt.burst).RateLimitFailFast()
+
+			for range 10 {
+				if _, err := client.Build().Uri("/items").Get(); err != nil && !errors.Is(err, apifast.ErrRateLimited) {
+					t.Fatalf("Get() error = %v", err)
+				}
+			}
+			if got := len(mock.Requests()); got != tt.wantSent {
+				t.Errorf("sent %d requests, want %d", got, tt.wantSent)
+			}
+		})
+	}
+}
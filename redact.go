@@ -0,0 +1,187 @@
+package apifast
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// redacted replaces sensitive values in dumps and recordings
+const redacted = "[REDACTED]"
+
+// defaultRedactedHeaders are the headers whose values never appear in dumps
+var defaultRedactedHeaders = []string{
+	fasthttp.HeaderAuthorization,
+	fasthttp.HeaderProxyAuthorization,
+	fasthttp.HeaderCookie,
+	fasthttp.HeaderSetCookie,
+	"X-Api-Key",
+}
+
+// RedactHeaders adds header names whose values are replaced with [REDACTED]
+// in debug dumps, HAR recordings, logs and curl commands. Authorization,
+// Proxy-Authorization, Cookie, Set-Cookie, X-Api-Key and the header carrying
+// the request's APIKey are always redacted.
+func (c *Client) RedactHeaders(names ...string) *Client {
+	c.redactHeaders = append(slices.Clip(c.redactHeaders), names...)
+	return c
+}
+
+// RedactFields adds JSON body fields, form fields and query parameters whose
+// values are replaced with [REDACTED] in debug dumps, HAR recordings, logs and
+// curl commands. Field names match at any depth of a JSON document. OAuth2
+// tokens and client secrets and the query parameter carrying the request's
+// APIKey are always redacted.
+func (c *Client) RedactFields(names ...string) *Client {
+	c.redactFields = append(slices.Clip(c.redactFields), names...)
+	return c
+}
+
+// defaultRedactedFields are the body fields and query parameters whose values
+// never appear in dumps, those carrying OAuth2 credentials and tokens
+var defaultRedactedFields = []string{
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"client_secret",
+}
+
+// redactor hides the sensitive values of a request in dumps and recordings
+type redactor struct {
+	c   *Client
+	key APIKey // API key of the request, hidden under its header or parameter name
+}
+
+// redactor returns the redactor of the request
+func (b *FastBuilder) redactor() redactor {
+	return redactor{c: b.client, key: b.auth().APIKey}
+}
+
+// redactsHeader reports whether the value of header name must be hidden
+func (r redactor) redactsHeader(name string) bool {
+	if r.key.Key != "" && r.key.In == APIKeyInHeader && strings.EqualFold(r.key.name(), name) {
+		return true
+	}
+	return containsFold(defaultRedactedHeaders, name) || containsFold(r.c.redactHeaders, name)
+}
+
+// redactsField reports whether the value of a body field or query parameter
+// must be hidden
+func (r redactor) redactsField(name string) bool {
+	if r.key.Key != "" && r.key.In == APIKeyInQuery && strings.EqualFold(r.key.name(), name) {
+		return true
+	}
+	return containsFold(defaultRedactedFields, name) || containsFold(r.c.redactFields, name)
+}
+
+// containsFold reports whether names contains name, ignoring case
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerValue returns a header value as it may appear in dumps
+func (r redactor) headerValue(key, value []byte) string {
+	if r.redactsHeader(string(key)) {
+		return redacted
+	}
+	return string(value)
+}
+
+// redactURL returns uri with the values of redacted query parameters hidden
+func (r redactor) redactURL(uri *fasthttp.URI) string {
+	// The parsed arguments include parameters set after the URI was parsed
+	query := uri.QueryArgs().QueryString()
+	if len(query) == 0 {
+		return uri.String()
+	}
+	var u fasthttp.URI
+	uri.CopyTo(&u)
+	u.SetQueryString(r.redactForm(query))
+	return u.String()
+}
+
+// redactBody returns body with the values of redacted fields hidden, for JSON
+// and urlencoded bodies; other bodies are returned as is
+func (r redactor) redactBody(body []byte, contentType []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	mediaType, _, _ := strings.Cut(strings.ToLower(string(contentType)), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return []byte(r.redactForm(body))
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var doc interface{}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return body
+		}
+		doc, found := r.redactJSON(doc)
+		if !found {
+			return body
+		}
+		redactedBody, err := json.Marshal(doc)
+		if err != nil {
+			return body
+		}
+		return redactedBody
+	}
+	return body
+}
+
+// redactForm hides the values of redacted fields in a urlencoded string,
+// which is returned as is if it has none
+func (r redactor) redactForm(form []byte) string {
+	values, err := url.ParseQuery(string(form))
+	if err != nil {
+		return string(form)
+	}
+	found := false
+	for key := range values {
+		if r.redactsField(key) {
+			values[key] = []string{redacted}
+			found = true
+		}
+	}
+	if !found {
+		return string(form)
+	}
+	return values.Encode()
+}
+
+// redactJSON hides the values of redacted fields at any depth of a document,
+// reporting whether it found any
+func (r redactor) redactJSON(v interface{}) (interface{}, bool) {
+	found := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if r.redactsField(key) {
+				v[key] = redacted
+				found = true
+			} else {
+				var nested bool
+				v[key], nested = r.redactJSON(value)
+				found = found || nested
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			var nested bool
+			v[i], nested = r.redactJSON(value)
+			found = found || nested
+		}
+	}
+	return v, found
+}
@@ -0,0 +1,112 @@
+package apifast
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RedirectPolicy decides whether the redirect to next is followed. via lists
+// the URLs requested so far, oldest first. Returning false stops following
+// and hands the redirect response back to the caller.
+//
+// A 303, or a 301 or 302 answering a POST, is followed with a GET without a
+// body. Other redirects replay the method and body, so a PUT or PATCH is sent
+// again with its payload; with a streamed body they are not followed.
+// Credentials, cookies and hook headers are dropped once a redirect leaves the
+// host or downgrades from HTTPS to HTTP.
+type RedirectPolicy func(next string, via []string) bool
+
+// FollowRedirects makes the client follow up to max redirects per request; zero disables following
+func (c *Client) FollowRedirects(max int) *Client {
+	c.maxRedirects = max
+	return c
+}
+
+// RedirectPolicy sets a callback consulted before each redirect is followed
+func (c *Client) RedirectPolicy(policy RedirectPolicy) *Client {
+	c.redirectPolicy = policy
+	return c
+}
+
+// followRedirects re-sends req while resp is a redirect the client is allowed
+// to follow, reporting whether it left the original host
+func (b *FastBuilder) followRedirects(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, auth Auth, hooked []string) (crossed bool, err error) {
+	c := b.client
+	var via []string
+	for redirects := 0; c.maxRedirects > 0 && fasthttp.StatusCodeIsRedirect(resp.StatusCode()); redirects++ {
+		location := resp.Header.Peek("Location")
+		if len(location) == 0 {
+			return crossed, nil
+		}
+		if redirects >= c.maxRedirects {
+			return crossed, &permanentError{fmt.Errorf("stopped after %d redirects", c.maxRedirects)}
+		}
+
+		// Resolve the location against the current URL
+		current := req.URI().String()
+		next := fasthttp.AcquireURI()
+		req.URI().CopyTo(next)
+		next.UpdateBytes(location)
+		nextURL := next.String()
+		// Leaving the host or downgrading to plain HTTP exposes the credentials
+		trusted := string(next.Host()) == string(req.URI().Host()) &&
+			!(string(req.URI().Scheme()) == "https" && string(next.Scheme()) == "http")
+		fasthttp.ReleaseURI(next)
+
+		via = append(via, current)
+		if c.redirectPolicy != nil && !c.redirectPolicy(nextURL, via) {
+			return crossed, nil
+		}
+
+		// 303, and 301/302 after a POST, switch to a body-less GET. Other
+		// methods, and every method on 307/308, are replayed with their body,
+		// which a streamed body cannot be
+		code := resp.StatusCode()
+		if code == fasthttp.StatusSeeOther || (code != fasthttp.StatusTemporaryRedirect &&
+			code != fasthttp.StatusPermanentRedirect && req.Header.IsPost()) {
+			req.Header.SetMethod(fasthttp.MethodGet)
+			req.ResetBody()
+			req.Header.SetContentLength(0)
+			req.Header.Del("Content-Type")
+		} else if req.BodyStream() != nil {
+			return crossed, nil
+		}
+
+		req.SetRequestURI(nextURL)
+
+		// Credentials are not forwarded to other hosts or over plain HTTP
+		if !trusted {
+			crossed = true
+			stripCredentials(req, auth, hooked)
+			req.UseHostHeader = false
+		} else if !crossed && auth.APIKey.Key != "" && auth.APIKey.In == APIKeyInQuery {
+			// A key in the query is lost with the URL the location replaced
+			auth.APIKey.apply(req)
+		}
+
+		streamBody := resp.StreamBody
+		resp.Reset()
+		resp.StreamBody = streamBody
+		if err := c.roundTrip(ctx, req, resp); err != nil {
+			return crossed, err
+		}
+	}
+	return crossed, nil
+}
+
+// stripCredentials removes from req what authenticates it to its original
+// host: authorization headers, cookies, the API key and the headers added by
+// request hooks, such as HMAC signatures
+func stripCredentials(req *fasthttp.Request, auth Auth, hooked []string) {
+	req.Header.Del(fasthttp.HeaderAuthorization)
+	req.Header.Del(fasthttp.HeaderProxyAuthorization)
+	req.Header.DelAllCookies()
+	if auth.APIKey.Key != "" {
+		auth.APIKey.strip(req)
+	}
+	for _, name := range hooked {
+		req.Header.Del(name)
+	}
+}
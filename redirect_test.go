@@ -0,0 +1,191 @@
+package apifast_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestRedirectCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string // redirect target of https://a.example/start
+		auth     apifast.Auth
+		headers  []apifast.Header
+		hook     bool   // sign requests with an HMACSigner hook
+		header   string // header checked on the redirected request
+		query    string // query parameter checked on the redirected request
+		kept     bool   // whether the credential must reach the target
+	}{
+		{
+			name:     "bearer token is kept on the same host",
+			location: "/next",
+			auth:     apifast.Auth{Token: "secret"},
+			header:   "Authorization",
+			kept:     true,
+		},
+		{
+			name:     "bearer token is stripped on another host",
+			location: "https://b.example/next",
+			auth:     apifast.Auth{Token: "secret"},
+			header:   "Authorization",
+		},
+		{
+			name:     "basic credentials are stripped on another host",
+			location: "https://b.example/next",
+			auth:     apifast.Auth{Username: "ada", Password: "secret"},
+			header:   "Authorization",
+		},
+		{
+			name:     "API key header is stripped on another host",
+			location: "https://b.example/next",
+			auth:     apifast.Auth{APIKey: apifast.APIKey{Key: "secret"}},
+			header:   "X-API-Key",
+		},
+		{
+			name:     "API key query parameter is stripped on another host",
+			location: "https://b.example/next",
+			auth:     apifast.Auth{APIKey: apifast.APIKey{Key: "secret", In: apifast.APIKeyInQuery}},
+			query:    "api_key",
+		},
+		{
+			name:     "API key query parameter is kept on the same host",
+			location: "/next",
+			auth:     apifast.Auth{APIKey: apifast.APIKey{Key: "secret", In: apifast.APIKeyInQuery}},
+			query:    "api_key",
+			kept:     true,
+		},
+		{
+			name:     "bearer token is stripped on a downgrade to HTTP",
+			location: "http://a.example/next",
+			auth:     apifast.Auth{Token: "secret"},
+			header:   "Authorization",
+		},
+		{
+			name:     "API key query parameter is stripped on a downgrade to HTTP",
+			location: "http://a.example/next",
+			auth:     apifast.Auth{APIKey: apifast.APIKey{Key: "secret", In: apifast.APIKeyInQuery}},
+			query:    "api_key",
+		},
+		{
+			name:     "cookies are stripped on another host",
+			location: "https://b.example/next",
+			headers:  []apifast.Header{{Tag: "Cookie", Value: "session=secret"}},
+			header:   "Cookie",
+		},
+		{
+			name:     "proxy credentials are stripped on another host",
+			location: "https://b.example/next",
+			headers:  []apifast.Header{{Tag: "Proxy-Authorization", Value: "Basic c2VjcmV0"}},
+			header:   "Proxy-Authorization",
+		},
+		{
+			name:     "hook signatures are stripped on another host",
+			location: "https://b.example/next",
+			hook:     true,
+			header:   "X-Signature",
+		},
+		{
+			name:     "hook signatures are kept on the same host",
+			location: "/next",
+			hook:     true,
+			header:   "X-Signature",
+			kept:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On("GET", "https://a.example/start*").Reply(302, "").Header("Location", tt.location)
+			mock.On("GET", "*").Reply(200, "ok")
+			client := apifast.NewClient().Transport(mock).FollowRedirects(5).Auth(tt.auth)
+			if tt.hook {
+				client.OnBeforeRequest(apifast.HMACSigner{Secret: []byte("secret")}.Sign)
+			}
+
+			resp, err := client.Build().Uri("https://a.example/start").Headers(tt.headers).Get()
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if resp.Code != 200 {
+				t.Fatalf("status = %d, want 200", resp.Code)
+			}
+			requests := mock.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("sent %d requests, want 2", len(requests))
+			}
+
+			first, redirected := requests[0], requests[1]
+			var sent, forwarded bool
+			if tt.header != "" {
+				sent = first.Header.Get(tt.header) != ""
+				forwarded = redirected.Header.Get(tt.header) != ""
+			} else {
+				sent = queryParam(t, first.URL, tt.query) != ""
+				forwarded = queryParam(t, redirected.URL, tt.query) != ""
+			}
+			if !sent {
+				t.Fatalf("credential missing from the first request %s", first.URL)
+			}
+			if forwarded != tt.kept {
+				t.Errorf("redirected request to %s carries the credential = %t, want %t", redirected.URL, forwarded, tt.kept)
+			}
+		})
+	}
+}
+
+func TestRedirectMethods(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		status     int
+		wantMethod string
+		wantBody   bool
+	}{
+		{"303 switches to GET", "PUT", 303, "GET", false},
+		{"302 switches POST to GET", "POST", 302, "GET", false},
+		{"301 switches POST to GET", "POST", 301, "GET", false},
+		{"302 replays PUT with its body", "PUT", 302, "PUT", true},
+		{"301 replays PATCH with its body", "PATCH", 301, "PATCH", true},
+		{"307 replays POST with its body", "POST", 307, "POST", true},
+		{"308 replays POST with its body", "POST", 308, "POST", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On(tt.method, "/start").Reply(tt.status, "").Header("Location", "/next")
+			mock.On(tt.wantMethod, "/next").Reply(200, "ok")
+			client := apifast.NewClient().Transport(mock).FollowRedirects(5)
+
+			resp, err := client.Build().Uri("https://a.example/start").Payload([]byte("data")).Method(tt.method).Do()
+			if err != nil {
+				t.Fatalf("%s error = %v", tt.method, err)
+			}
+			if resp.Code != 200 {
+				t.Fatalf("status = %d, want 200", resp.Code)
+			}
+			requests := mock.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("sent %d requests, want 2", len(requests))
+			}
+			redirected := requests[1]
+			if redirected.Method != tt.wantMethod {
+				t.Errorf("redirected with %s, want %s", redirected.Method, tt.wantMethod)
+			}
+			if hasBody := len(redirected.Body) > 0; hasBody != tt.wantBody {
+				t.Errorf("redirected request has a body = %t, want %t", hasBody, tt.wantBody)
+			}
+		})
+	}
+}
+
+// queryParam returns the value of a query parameter of rawURL
+func queryParam(t *testing.T, rawURL, name string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("invalid request URL %q: %v", rawURL, err)
+	}
+	return u.Query().Get(name)
+}
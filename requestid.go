@@ -0,0 +1,54 @@
+package apifast
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// RequestID sends a request ID with every request in header, X-Request-ID if
+// empty. The ID is read from the request context (see WithRequestID) or
+// generated per call, stays the same across its retries, and is recorded in ConnError,
+// StatusError and log events so calls can be traced across services.
+func (c *Client) RequestID(header string) *Client {
+	if header == "" {
+		header = "X-Request-ID"
+	}
+	c.requestIDHeader = header
+	return c
+}
+
+// assignRequestID picks the ID of the request if the client sends one
+func (b *FastBuilder) assignRequestID() {
+	if b.client.requestIDHeader == "" || b.requestID != "" {
+		return
+	}
+	if b.ctx != nil {
+		if id, ok := RequestIDFromContext(b.ctx); ok {
+			b.requestID = id
+			return
+		}
+	}
+	b.requestID = newRequestID()
+}
+
+// newRequestID generates a random 128-bit request ID
+func newRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,50 @@
+package apifast
+
+// IsSuccess reports whether the response has a 2xx status code
+func (r *Response) IsSuccess() bool {
+	return r.Code >= 200 && r.Code < 300
+}
+
+// IsError reports whether the response has a status code of 400 or above
+func (r *Response) IsError() bool {
+	return r.Code >= 400
+}
+
+// Header returns the first value of the response header key, case-insensitively
+func (r *Response) Header(key string) string {
+	return r.Headers.Get(key)
+}
+
+// ContentType returns the Content-Type header of the response
+func (r *Response) ContentType() string {
+	return r.Headers.Get("Content-Type")
+}
+
+// Bytes returns the response body
+func (r *Response) Bytes() []byte {
+	return r.Body
+}
+
+// String returns the response body as a string
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
+// JSON decodes the JSON response body into v with the client's JSON codec,
+// whatever content type the server reports
+func (r *Response) JSON(v interface{}) error {
+	codec := r.codec
+	if codec == nil {
+		codec = stdJSON{}
+	}
+	return codec.Unmarshal(r.Body, v)
+}
+
+// Map decodes a JSON object response body into a map
+func (r *Response) Map() (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := r.JSON(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,294 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// downloadChunkSize is the number of bytes fetched by each request of Download
+const downloadChunkSize = 8 << 20
+
+// errRangeRestart reports a partial download that no longer matches the remote file
+var errRangeRestart = errors.New("remote file changed, restarting download")
+
+// Download saves the response body to the file at path with Range requests of
+// up to 8 MiB, so an interrupted transfer resumes where it stopped. Data is
+// written to path+".part" until the download completes, and a later Download
+// of the same path continues a failed one. The ETag or Last-Modified validator
+// of the first response is sent in If-Range, and a part that no longer matches
+// the remote file is discarded. Servers that ignore Range send the whole body
+// at once. Within a call a stalled transfer is resumed up to the retry count
+// times, 5 if unset, after the retry backoff.
+func (b *FastBuilder) Download(path string) (*Response, error) {
+	if b.method == "" {
+		b.method = "GET"
+	}
+	ctx := b.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	resumes := b.options.Retry.Count
+	if resumes <= 0 {
+		resumes = 5
+	}
+	backoff := b.options.Retry.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+
+	d, err := openDownload(path)
+	if err != nil {
+		return nil, err
+	}
+	defer d.close()
+
+	for failures := 0; ; {
+		resp, done, err := b.downloadChunk(d)
+		// The first range reveals the size, the rest can then be fetched in parallel
+		if err == nil && !done && b.segments > 1 && d.total > 0 {
+			resp, err = b.downloadSegments(ctx, d, resumes, backoff)
+			done = err == nil
+		}
+		switch {
+		case err == nil && done:
+			return resp, d.complete(b.expectedChecksum())
+		case err == nil:
+			failures = 0
+			continue
+		case errors.Is(err, errRangeRestart):
+			if err := d.reset(); err != nil {
+				return resp, err
+			}
+		default:
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) && statusErr.Code != 429 && statusErr.Code < 500 {
+				return resp, err
+			}
+		}
+
+		failures++
+		if failures > resumes || ctx.Err() != nil {
+			return resp, err
+		}
+		if err := sleep(ctx, backoff.Delay(failures)); err != nil {
+			return resp, b.wrapError(err)
+		}
+	}
+}
+
+// downloadChunk fetches the next range of d, reporting whether the file is complete
+func (b *FastBuilder) downloadChunk(d *download) (*Response, bool, error) {
+	resp, err := b.rangeRequest(d.validator, d.offset, d.offset+downloadChunkSize-1).makeRequest()
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == fasthttp.StatusRequestedRangeNotSatisfiable {
+		// Only a part that is already complete cannot be extended
+		if _, total, ok := parseContentRange(resp.Headers.Get("Content-Range")); ok && total == d.offset && d.offset > 0 {
+			return resp, true, nil
+		}
+		return resp, false, errRangeRestart
+	}
+	if err != nil {
+		return resp, false, err
+	}
+	body := resp.Body
+
+	// The server sent the whole file
+	if resp.Code != fasthttp.StatusPartialContent {
+		if err := d.reset(); err != nil {
+			return resp, false, err
+		}
+		return resp, true, d.write(resp, body)
+	}
+
+	total, err := d.checkRange(resp, d.offset)
+	if err != nil {
+		return resp, false, err
+	}
+	d.total = total
+	if err := d.write(resp, body); err != nil {
+		return resp, false, err
+	}
+	return resp, total >= 0 && d.offset >= total || len(body) == 0, nil
+}
+
+// rangeRequest returns a copy of the builder fetching the bytes from start to
+// end of the remote file, unless it no longer matches validator
+func (b *FastBuilder) rangeRequest(validator string, start, end int64) *FastBuilder {
+	conn := *b
+	conn.result, conn.writer, conn.downloadPath = nil, nil, ""
+	// The expected checksum covers the whole file, not a range
+	conn.checksum = nil
+	// Ranges refer to the stored representation, so it must not be compressed
+	headers := append(slices.Clip(b.options.Headers),
+		Header{Tag: "Accept-Encoding", Value: "identity"},
+		Header{Tag: "Range", Value: fmt.Sprintf("bytes=%d-%d", start, end)})
+	if validator != "" {
+		headers = append(headers, Header{Tag: "If-Range", Value: validator})
+	}
+	conn.options.Headers = headers
+	return &conn
+}
+
+// download is a file being downloaded into its part file
+type download struct {
+	path      string
+	f         *os.File // the part file
+	offset    int64    // bytes downloaded so far, without gaps
+	total     int64    // size of the remote file, -1 if unknown
+	validator string   // ETag or Last-Modified of the remote file
+}
+
+// openDownload opens the part file of path, continuing a previous download
+// if its validator is known
+func openDownload(path string) (*download, error) {
+	f, err := os.OpenFile(path+".part", os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download file: %w", err)
+	}
+	d := &download{path: path, f: f, total: -1}
+	var saved int64 = -1
+	if meta, err := os.ReadFile(d.metaPath()); err == nil {
+		validator, offset, _ := strings.Cut(strings.TrimSpace(string(meta)), "\n")
+		d.validator = validator
+		if offset, err := strconv.ParseInt(offset, 10, 64); err == nil {
+			saved = offset
+		}
+	}
+	// A part without a validator cannot be matched to the remote file
+	if d.validator == "" {
+		return d, d.reset()
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read download file: %w", err)
+	}
+	// Segmented downloads record how far the part is free of gaps
+	d.offset = info.Size()
+	if saved >= 0 && saved < d.offset {
+		d.offset = saved
+	}
+	return d, nil
+}
+
+// metaPath is the file holding the validator of the download and, for
+// segmented downloads, the length of the part without gaps
+func (d *download) metaPath() string {
+	return d.path + ".part.meta"
+}
+
+// checkRange verifies that a 206 response holds the remote file from start
+// on, returning the file size, -1 if unknown
+func (d *download) checkRange(resp *Response, start int64) (int64, error) {
+	first, total, ok := parseContentRange(resp.Headers.Get("Content-Range"))
+	if !ok || first != start {
+		return 0, errRangeRestart
+	}
+	if etag := strongETag(resp); etag != "" && strings.HasPrefix(d.validator, `"`) && etag != d.validator {
+		return 0, errRangeRestart
+	}
+	return total, nil
+}
+
+// write appends body, a range or the whole file, and records its validator
+func (d *download) write(resp *Response, body []byte) error {
+	if d.offset == 0 {
+		validator := resp.Headers.Get("ETag")
+		if validator == "" || strings.HasPrefix(validator, "W/") {
+			validator = resp.Headers.Get("Last-Modified")
+		}
+		d.validator = validator
+		if validator != "" {
+			if err := os.WriteFile(d.metaPath(), []byte(validator), 0o644); err != nil {
+				return fmt.Errorf("failed to write download file: %w", err)
+			}
+		}
+	}
+	n, err := d.f.WriteAt(body, d.offset)
+	d.offset += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write download file: %w", err)
+	}
+	return nil
+}
+
+// reset discards the downloaded part
+func (d *download) reset() error {
+	d.offset, d.total, d.validator = 0, -1, ""
+	os.Remove(d.metaPath())
+	if err := d.f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write download file: %w", err)
+	}
+	return nil
+}
+
+// complete moves the finished part file to the download path once it
+// matches checks, or discards it
+func (d *download) complete(checks []*checksum) error {
+	if err := d.f.Close(); err != nil {
+		return fmt.Errorf("failed to write download file: %w", err)
+	}
+	if err := verifyFile(d.path+".part", checks); err != nil {
+		os.Remove(d.path + ".part")
+		os.Remove(d.metaPath())
+		return err
+	}
+	if err := os.Rename(d.path+".part", d.path); err != nil {
+		return fmt.Errorf("failed to complete download: %w", err)
+	}
+	os.Remove(d.metaPath())
+	return nil
+}
+
+// close releases the part file of an unfinished download
+func (d *download) close() {
+	d.f.Close()
+}
+
+// strongETag returns the ETag of resp unless it is missing or weak, which If-Range does not accept
+func strongETag(resp *Response) string {
+	etag := resp.Headers.Get("ETag")
+	if strings.HasPrefix(etag, "W/") {
+		return ""
+	}
+	return etag
+}
+
+// parseContentRange reads the first byte and total size of a Content-Range
+// header such as "bytes 100-199/1000" or "bytes */1000"; total is -1 if unknown
+func parseContentRange(header string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(header), "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	byteRange, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		var err error
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if byteRange == "*" {
+		return 0, total, true
+	}
+	first, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
@@ -0,0 +1,196 @@
+package apifast_test
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+	"github.com/eantaru/apifast/apifasttest"
+)
+
+// fileServer serves content with Range support, recording the Range header
+// of every request; failures lists ranges answered 503 once each
+type fileServer struct {
+	content  []byte
+	etag     string
+	noRanges bool
+
+	mu       sync.Mutex
+	ranges   []string
+	failures map[string]bool
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The adaptor shares header memory with the request, which is reused
+	byteRange := strings.Clone(r.Header.Get("Range"))
+	s.mu.Lock()
+	s.ranges = append(s.ranges, byteRange)
+	fail := s.failures[byteRange]
+	delete(s.failures, byteRange)
+	s.mu.Unlock()
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if s.noRanges {
+		w.Write(s.content)
+		return
+	}
+	w.Header().Set("ETag", s.etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(s.content))
+}
+
+// requests returns the Range headers received so far
+func (s *fileServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+// testContent returns n bytes that differ at every offset modulo a prime
+func testContent(n int) []byte {
+	content := make([]byte, n)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := testContent(1 << 20)
+	tests := []struct {
+		name      string
+		part      []byte // part file left by an earlier download
+		meta      string // validator file left by an earlier download
+		noRanges  bool
+		wantRange string // Range header of the first request
+	}{
+		{
+			name:      "fresh download",
+			wantRange: "bytes=0-8388607",
+		},
+		{
+			name:      "part with a matching validator is continued",
+			part:      content[:300000],
+			meta:      `"v1"`,
+			wantRange: "bytes=300000-8688607",
+		},
+		{
+			name:      "part with a stale validator is replaced",
+			part:      content[:300000],
+			meta:      `"v0"`,
+			wantRange: "bytes=300000-8688607",
+		},
+		{
+			name:      "part without a validator is discarded",
+			part:      bytes.Repeat([]byte("x"), 300000),
+			wantRange: "bytes=0-8388607",
+		},
+		{
+			name:      "server without range support sends the whole file",
+			part:      content[:300000],
+			meta:      `"v1"`,
+			noRanges:  true,
+			wantRange: "bytes=300000-8688607",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := &fileServer{content: content, etag: `"v1"`, noRanges: tt.noRanges}
+			srv := apifasttest.NewHTTPServer(files)
+			defer srv.Close()
+
+			path := filepath.Join(t.TempDir(), "file.bin")
+			if tt.part != nil {
+				if err := os.WriteFile(path+".part", tt.part, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.meta != "" {
+				if err := os.WriteFile(path+".part.meta", []byte(tt.meta), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := srv.Client.Build().Uri("/file.bin").Download(path); err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			checkDownload(t, path, content)
+			if ranges := files.requests(); len(ranges) == 0 || ranges[0] != tt.wantRange {
+				t.Errorf("requested ranges %q, want %q first", ranges, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestDownloadSegments(t *testing.T) {
+	// Large enough for segments beyond the first 8 MiB range
+	content := testContent(10<<20 + 12345)
+	tests := []struct {
+		name         string
+		segments     int
+		failures     []string // ranges answered 503 once
+		wantRequests int
+	}{
+		{name: "one segment fetches sequential ranges", segments: 1, wantRequests: 2},
+		{name: "the rest is split across segments", segments: 4, wantRequests: 5},
+		{
+			name:         "a failed segment is retried",
+			segments:     4,
+			failures:     []string{"bytes=8915983-9443357"},
+			wantRequests: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := &fileServer{content: content, etag: `"v1"`, failures: make(map[string]bool)}
+			for _, r := range tt.failures {
+				files.failures[r] = true
+			}
+			srv := apifasttest.NewHTTPServer(files)
+			defer srv.Close()
+
+			path := filepath.Join(t.TempDir(), "file.bin")
+			_, err := srv.Client.Build().
+				Uri("/file.bin").
+				DownloadSegments(tt.segments).
+				RetryBackoff(apifast.ConstantBackoff(time.Millisecond)).
+				Download(path)
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			checkDownload(t, path, content)
+			if ranges := files.requests(); len(ranges) != tt.wantRequests {
+				t.Errorf("sent %d range requests %q, want %d", len(ranges), ranges, tt.wantRequests)
+			}
+		})
+	}
+}
+
+// checkDownload verifies that path holds content and no part files are left
+func checkDownload(t *testing.T, path string, content []byte) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading download: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("downloaded %d bytes that differ from the %d byte file", len(got), len(content))
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".part") || strings.HasSuffix(e.Name(), ".meta") {
+			t.Errorf("part file %s left behind", e.Name())
+		}
+	}
+}
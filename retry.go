@@ -0,0 +1,294 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RetryPolicy controls how failed attempts are retried. By default network
+// errors, 429 and 5xx responses are. A Retry-After header on 429 and 503
+// responses replaces the backoff delay before the next attempt.
+type RetryPolicy struct {
+	Count          int                                          // Maximum number of retries after the first attempt
+	Backoff        Backoff                                      // Delay schedule between attempts, DefaultBackoff if nil
+	AttemptTimeout time.Duration                                // Timeout applied to each individual attempt
+	MaxRetryAfter  time.Duration                                // Longest Retry-After honored, beyond which the response is returned; unbounded if zero
+	OnRetry        func(attempt int, resp *Response, err error) // Called before each retry with the failed outcome
+
+	Statuses       []int                                // Response statuses retried, 429 and 5xx if empty
+	Errors         []error                              // Errors retried, matched with errors.Is; any error if empty
+	Condition      func(resp *Response, err error) bool // Decides on retries instead of Statuses and Errors if set
+	IdempotentOnly bool                                 // Only retry idempotent methods, or requests with an Idempotency-Key
+}
+
+// Backoff computes the delay to wait before a retry
+type Backoff interface {
+	// Delay returns the wait before the given retry, starting at 1
+	Delay(retry int) time.Duration
+}
+
+// BackoffFunc adapts a plain function to the Backoff interface
+type BackoffFunc func(retry int) time.Duration
+
+// Delay calls f(retry)
+func (f BackoffFunc) Delay(retry int) time.Duration {
+	return f(retry)
+}
+
+// ConstantBackoff waits the same duration before every retry
+func ConstantBackoff(d time.Duration) Backoff {
+	return BackoffFunc(func(int) time.Duration { return d })
+}
+
+// ExponentialBackoff grows the delay by Multiplier on every retry, capped at Max
+type ExponentialBackoff struct {
+	Initial    time.Duration // Delay before the first retry
+	Max        time.Duration // Upper bound for a single delay, unbounded if zero
+	Multiplier float64       // Growth factor, 2 if zero
+	Jitter     float64       // Randomization factor in [0, 1] applied to each delay
+}
+
+// DefaultBackoff is used when a retry policy does not specify one
+var DefaultBackoff = ExponentialBackoff{
+	Initial:    100 * time.Millisecond,
+	Max:        10 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Delay returns Initial * Multiplier^(retry-1), capped and jittered
+func (e ExponentialBackoff) Delay(retry int) time.Duration {
+	multiplier := e.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(e.Initial) * math.Pow(multiplier, float64(retry-1))
+	if e.Max > 0 && delay > float64(e.Max) {
+		delay = float64(e.Max)
+	}
+	if e.Jitter > 0 {
+		delay += delay * e.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// Retry sets the maximum number of retries after the first attempt
+func (b *FastBuilder) Retry(count int) *FastBuilder {
+	b.options.Retry.Count = count
+	return b
+}
+
+// RetryBackoff sets the delay schedule between retries
+func (b *FastBuilder) RetryBackoff(backoff Backoff) *FastBuilder {
+	b.options.Retry.Backoff = backoff
+	return b
+}
+
+// AttemptTimeout gives each attempt a fresh timeout, so a slow attempt can be
+// retried while the call stays within its TotalTimeout
+func (b *FastBuilder) AttemptTimeout(timeout time.Duration) *FastBuilder {
+	b.options.Retry.AttemptTimeout = timeout
+	return b
+}
+
+// RetryAttemptTimeout sets the timeout applied to each individual attempt.
+//
+// Deprecated: use AttemptTimeout.
+func (b *FastBuilder) RetryAttemptTimeout(timeout time.Duration) *FastBuilder {
+	return b.AttemptTimeout(timeout)
+}
+
+// MaxRetryAfter sets the longest Retry-After wait honored before a retry
+func (b *FastBuilder) MaxRetryAfter(d time.Duration) *FastBuilder {
+	b.options.Retry.MaxRetryAfter = d
+	return b
+}
+
+// RetryOn sets the response statuses that are retried, replacing 429 and 5xx
+func (b *FastBuilder) RetryOn(statuses ...int) *FastBuilder {
+	b.options.Retry.Statuses = statuses
+	return b
+}
+
+// RetryOnErrors restricts retried errors to those matching one of errs
+// under errors.Is, such as ErrTimeout
+func (b *FastBuilder) RetryOnErrors(errs ...error) *FastBuilder {
+	b.options.Retry.Errors = errs
+	return b
+}
+
+// RetryIf decides on retries with condition instead of the status and error
+// lists. The error is one of the exported error types, as returned to callers.
+func (b *FastBuilder) RetryIf(condition func(resp *Response, err error) bool) *FastBuilder {
+	b.options.Retry.Condition = condition
+	return b
+}
+
+// RetryIdempotentOnly stops retries of methods that are not idempotent, such
+// as POST and PATCH, unless the request carries an Idempotency-Key header
+func (b *FastBuilder) RetryIdempotentOnly() *FastBuilder {
+	b.options.Retry.IdempotentOnly = true
+	return b
+}
+
+// OnRetry registers a hook called before each retry with the failed outcome
+func (b *FastBuilder) OnRetry(hook func(attempt int, resp *Response, err error)) *FastBuilder {
+	b.options.Retry.OnRetry = hook
+	return b
+}
+
+// retry runs attempts of the request until one succeeds or the policy is exhausted
+func (b *FastBuilder) retry(ctx context.Context) (*Response, error) {
+	policy := b.options.Retry
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		// Record where the attempt went so its retry is labeled with that host
+		attemptCtx := ctx
+		var sent *attemptHost
+		if b.client.metrics != nil {
+			sent = &attemptHost{}
+			attemptCtx = context.WithValue(ctx, attemptHostKey{}, sent)
+		}
+		resp, err := b.attempt(attemptCtx, policy.AttemptTimeout)
+		if attempt > policy.Count || ctx.Err() != nil || !b.retryable(resp, err) || !b.rewindBody() {
+			return resp, err
+		}
+
+		// The server's Retry-After takes precedence over the backoff schedule
+		delay := backoff.Delay(attempt)
+		if wait, ok := retryAfter(resp); ok {
+			if policy.MaxRetryAfter > 0 && wait > policy.MaxRetryAfter {
+				return resp, err
+			}
+			delay = wait
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, resp, err)
+		}
+		if b.client.metrics != nil {
+			b.client.metrics.retried(b, sent.get())
+		}
+		if b.client.logger != nil {
+			b.logRetry(attempt, resp, err)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends the request once, bounded by the per-attempt timeout if set
+func (b *FastBuilder) attempt(ctx context.Context, timeout time.Duration) (*Response, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if b.hedgeable() {
+		return b.hedge(ctx)
+	}
+	return b.send(ctx)
+}
+
+// permanentError marks an attempt failure that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// retryable reports whether the outcome of an attempt should be retried
+func (b *FastBuilder) retryable(resp *Response, err error) bool {
+	policy := b.options.Retry
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	if policy.IdempotentOnly && !b.idempotent() {
+		return false
+	}
+	if err != nil {
+		err = b.wrapError(err)
+	}
+	if policy.Condition != nil {
+		return policy.Condition(resp, err)
+	}
+
+	if err != nil {
+		if len(policy.Errors) == 0 {
+			return true
+		}
+		for _, target := range policy.Errors {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(policy.Statuses) == 0 {
+		return resp.Code == fasthttp.StatusTooManyRequests || resp.Code >= 500
+	}
+	return slices.Contains(policy.Statuses, resp.Code)
+}
+
+// idempotent reports whether the request can safely be sent more than once
+func (b *FastBuilder) idempotent() bool {
+	if idempotentMethod(b.method) || b.idempotencyKey != "" {
+		return true
+	}
+	for _, h := range b.options.Headers {
+		if strings.EqualFold(h.Tag, "Idempotency-Key") {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the wait requested by the Retry-After header of a 429 or
+// 503 response, given in seconds or as an HTTP date
+func retryAfter(resp *Response) (time.Duration, bool) {
+	if resp == nil || (resp.Code != fasthttp.StatusTooManyRequests && resp.Code != fasthttp.StatusServiceUnavailable) {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Headers.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,203 @@
+package apifast_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestExponentialBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff apifast.ExponentialBackoff
+		retry   int
+		want    time.Duration
+	}{
+		{"first retry waits the initial delay", apifast.ExponentialBackoff{Initial: 100 * time.Millisecond}, 1, 100 * time.Millisecond},
+		{"delay doubles by default", apifast.ExponentialBackoff{Initial: 100 * time.Millisecond}, 3, 400 * time.Millisecond},
+		{"delay grows by the multiplier", apifast.ExponentialBackoff{Initial: 10 * time.Millisecond, Multiplier: 3}, 3, 90 * time.Millisecond},
+		{"delay is capped at max", apifast.ExponentialBackoff{Initial: 100 * time.Millisecond, Max: 250 * time.Millisecond}, 5, 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.backoff.Delay(tt.retry); got != tt.want {
+				t.Errorf("Delay(%d) = %s, want %s", tt.retry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	backoff := apifast.ExponentialBackoff{Initial: 100 * time.Millisecond, Jitter: 0.2}
+	for range 100 {
+		if got := backoff.Delay(2); got < 160*time.Millisecond || got > 240*time.Millisecond {
+			t.Fatalf("Delay(2) = %s, want within 20%% of 200ms", got)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		routes       func(m *apifast.MockTransport)
+		configure    func(b *apifast.FastBuilder) *apifast.FastBuilder
+		wantAttempts int
+		wantCode     int
+		wantErr      bool
+	}{
+		{
+			name: "5xx is retried until it succeeds",
+			routes: func(m *apifast.MockTransport) {
+				m.On("GET", "/items").Reply(503, "").Times(2)
+				m.On("GET", "/items").Reply(200, "ok")
+			},
+			configure:    func(b *apifast.FastBuilder) *apifast.FastBuilder { return b.Retry(3) },
+			wantAttempts: 3,
+			wantCode:     200,
+		},
+		{
+			name: "retries stop at the retry count",
+			routes: func(m *apifast.MockTransport) {
+				m.On("GET", "/items").Reply(500, "")
+			},
+			configure:    func(b *apifast.FastBuilder) *apifast.FastBuilder { return b.Retry(2) },
+			wantAttempts: 3,
+			wantCode:     500,
+			wantErr:      true,
+		},
+		{
+			name: "4xx is not retried",
+			routes: func(m *apifast.MockTransport) {
+				m.On("GET", "/items").Reply(404, "")
+			},
+			configure:    func(b *apifast.FastBuilder) *apifast.FastBuilder { return b.Retry(3) },
+			wantAttempts: 1,
+			wantCode:     404,
+			wantErr:      true,
+		},
+		{
+			name: "connection errors are retried",
+			routes: func(m *apifast.MockTransport) {
+				m.On("GET", "/items").Fail(errors.New("connection reset")).Times(1)
+				m.On("GET", "/items").Reply(200, "ok")
+			},
+			configure:    func(b *apifast.FastBuilder) *apifast.FastBuilder { return b.Retry(1) },
+			wantAttempts: 2,
+			wantCode:     200,
+		},
+		{
+			name: "429 is retried by default",
+			routes: func(m *apifast.MockTransport) {
+				m.On("GET", "/items").Reply(429, "").Times(1)
+				m.On("GET", "/items").Reply(200, "ok")
+			},
+			configure:    func(b *apifast.FastBuilder) *apifast.FastBuilder { return b.Retry(1) },
+			wantAttempts: 2,
+			wantCode:     200,
+		},
+		{
+			name: "RetryOn replaces the retried statuses",
+			routes: func(m *apifast.MockTransport) {
+				m.On("GET", "/items").Reply(500, "")
+			},
+			configure:    func(b *apifast.FastBuilder) *apifast.FastBuilder { return b.Retry(3).RetryOn(409) },
+			wantAttempts: 1,
+			wantCode:     500,
+			wantErr:      true,
+		},
+		{
+			name:   "RetryIdempotentOnly keeps POST from being retried",
+			method: "POST",
+			routes: func(m *apifast.MockTransport) {
+				m.On("POST", "/items").Reply(503, "")
+			},
+			configure:    func(b *apifast.FastBuilder) *apifast.FastBuilder { return b.Retry(3).RetryIdempotentOnly() },
+			wantAttempts: 1,
+			wantCode:     503,
+			wantErr:      true,
+		},
+		{
+			name:   "RetryIdempotentOnly retries POST with an Idempotency-Key",
+			method: "POST",
+			routes: func(m *apifast.MockTransport) {
+				m.On("POST", "/items").Reply(503, "").Times(1)
+				m.On("POST", "/items").Reply(201, "")
+			},
+			configure: func(b *apifast.FastBuilder) *apifast.FastBuilder {
+				return b.Retry(3).RetryIdempotentOnly().IdempotencyKey("key-1")
+			},
+			wantAttempts: 2,
+			wantCode:     201,
+		},
+		{
+			name: "Retry-After beyond MaxRetryAfter returns the response",
+			routes: func(m *apifast.MockTransport) {
+				m.On("GET", "/items").Reply(503, "").Header("Retry-After", "60")
+			},
+			configure:    func(b *apifast.FastBuilder) *apifast.FastBuilder { return b.Retry(3).MaxRetryAfter(time.Second) },
+			wantAttempts: 1,
+			wantCode:     503,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			tt.routes(mock)
+			client := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock)
+			b := tt.configure(client.Build().Uri("/items").RetryBackoff(apifast.ConstantBackoff(time.Millisecond)))
+			if tt.method != "" {
+				b.Method(tt.method)
+			}
+
+			resp, err := b.Do()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, want error %t", err, tt.wantErr)
+			}
+			if got := len(mock.Requests()); got != tt.wantAttempts {
+				t.Errorf("sent %d attempts, want %d", got, tt.wantAttempts)
+			}
+			if resp == nil {
+				t.Fatalf("Do() returned no response")
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", resp.Code, tt.wantCode)
+			}
+			if resp.Attempts != tt.wantAttempts {
+				t.Errorf("Response.Attempts = %d, want %d", resp.Attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryKeepsIdempotencyKey(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("POST", "/orders").Reply(503, "").Times(1)
+	mock.On("POST", "/orders").Reply(201, "")
+	client := apifast.NewClient().BaseURL("https://api.example.com").Transport(mock).IdempotencyKeys()
+	b := client.Build().Uri("/orders").Retry(1).RetryBackoff(apifast.ConstantBackoff(0))
+
+	if _, err := b.Post(); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if _, err := b.Post(); err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+
+	var keys []string
+	for _, req := range mock.Requests() {
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+	}
+	if len(keys) != 3 {
+		t.Fatalf("sent %d requests, want 3", len(keys))
+	}
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("retry sent key %q after %q, want the same key", keys[1], keys[0])
+	}
+	if keys[2] == keys[0] {
+		t.Errorf("second call reused key %q, want a new one", keys[2])
+	}
+}
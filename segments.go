@@ -0,0 +1,162 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// minSegmentSize keeps parallel ranges of small files from being too small to pay off
+const minSegmentSize = 256 << 10
+
+// DownloadSegments makes Download fetch up to n byte ranges of the file
+// concurrently, which speeds up large downloads from servers that limit the
+// bandwidth of a single connection. Servers without Range support are
+// downloaded in one response as before.
+func (b *FastBuilder) DownloadSegments(n int) *FastBuilder {
+	b.segments = n
+	return b
+}
+
+// segment is a byte range of a segmented download, end included
+type segment struct {
+	start, end int64
+}
+
+// downloadSegments fetches the rest of d with concurrent range requests, each
+// retried up to resumes times. Once a range fails for good the others are
+// canceled and the part is cut back to its bytes without gaps, so a later
+// Download continues from there.
+func (b *FastBuilder) downloadSegments(ctx context.Context, d *download, resumes int, backoff Backoff) (*Response, error) {
+	size := min(downloadChunkSize, max((d.total-d.offset+int64(b.segments)-1)/int64(b.segments), minSegmentSize))
+	var segments []segment
+	for start := d.offset; start < d.total; start += size {
+		segments = append(segments, segment{start, min(start+size, d.total) - 1})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		finished = make([]bool, len(segments))
+		next     int // first segment not yet written
+		last     *Response
+		failed   error
+	)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(b.segments, len(segments)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				resp, err := b.fetchSegment(ctx, d, segments[i], resumes, backoff)
+
+				mu.Lock()
+				if resp != nil {
+					last = resp
+				}
+				if err == nil {
+					// Advance the part past the segments written without gaps
+					finished[i] = true
+					for next < len(segments) && finished[next] {
+						next++
+					}
+					if next > 0 {
+						d.offset = segments[next-1].end + 1
+					}
+					err = d.saveOffset()
+				}
+				if err != nil && failed == nil {
+					failed = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range segments {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed == nil && ctx.Err() != nil {
+		failed = ctx.Err()
+	}
+	if failed != nil {
+		if err := d.f.Truncate(d.offset); err != nil {
+			return last, fmt.Errorf("failed to write download file: %w", err)
+		}
+		return last, failed
+	}
+	return last, nil
+}
+
+// fetchSegment downloads seg into the part file of d, retrying failures
+func (b *FastBuilder) fetchSegment(ctx context.Context, d *download, seg segment, resumes int, backoff Backoff) (*Response, error) {
+	for failures := 0; ; {
+		conn := b.rangeRequest(d.validator, seg.start, seg.end)
+		conn.ctx = ctx
+		resp, err := conn.makeRequest()
+		if err == nil {
+			err = d.writeSegment(resp, seg)
+		}
+		if err == nil {
+			return resp, nil
+		}
+
+		var statusErr *StatusError
+		if errors.Is(err, errRangeRestart) || (errors.As(err, &statusErr) && statusErr.Code != 429 && statusErr.Code < 500) {
+			return resp, err
+		}
+		failures++
+		if failures > resumes || ctx.Err() != nil {
+			return resp, err
+		}
+		if err := sleep(ctx, backoff.Delay(failures)); err != nil {
+			return resp, err
+		}
+	}
+}
+
+// writeSegment stores the body of a range response at its place in the part file
+func (d *download) writeSegment(resp *Response, seg segment) error {
+	if resp.Code != fasthttp.StatusPartialContent {
+		return errRangeRestart
+	}
+	if _, err := d.checkRange(resp, seg.start); err != nil {
+		return err
+	}
+	body := resp.Body
+	if int64(len(body)) != seg.end-seg.start+1 {
+		return fmt.Errorf("failed to write download file: got %d bytes for range %d-%d", len(body), seg.start, seg.end)
+	}
+	if _, err := d.f.WriteAt(body, seg.start); err != nil {
+		return fmt.Errorf("failed to write download file: %w", err)
+	}
+	return nil
+}
+
+// saveOffset records the validator and the length of the part without gaps
+func (d *download) saveOffset() error {
+	if d.validator == "" {
+		return nil
+	}
+	meta := fmt.Sprintf("%s\n%d", d.validator, d.offset)
+	if err := os.WriteFile(d.metaPath(), []byte(meta), 0o644); err != nil {
+		return fmt.Errorf("failed to write download file: %w", err)
+	}
+	return nil
+}
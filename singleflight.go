@@ -0,0 +1,108 @@
+package apifast
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Singleflight collapses concurrent identical GET calls of the client, with
+// the same URL, headers and authentication, into a single upstream call whose
+// response is shared with every caller, which cuts the load of cache
+// stampedes. Each caller still decodes the shared body into its own result.
+func (c *Client) Singleflight() *Client {
+	c.flights = &flightGroup{calls: make(map[string]*flight)}
+	return c
+}
+
+// flightGroup tracks the calls in flight by key
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is a call in flight whose outcome is shared once done is closed
+type flight struct {
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// collapse sends the call, or waits for the outcome of an identical call
+// already in flight
+func (b *FastBuilder) collapse(ctx context.Context) (*Response, error) {
+	g := b.client.flights
+	if g == nil || b.method != fasthttp.MethodGet || b.streaming() || b.dryRun {
+		return b.call(ctx)
+	}
+	key := b.flightKey()
+
+	g.mu.Lock()
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		// Stop waiting when the caller gives up, not when the first caller does
+		select {
+		case <-f.done:
+			return f.resp.share(), f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	f.resp, f.err = b.call(ctx)
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(f.done)
+	return f.resp.share(), f.err
+}
+
+// call sends the request, retrying according to the retry policy and moving
+// to the fallback hosts if needed
+func (b *FastBuilder) call(ctx context.Context) (*Response, error) {
+	resp, err := b.retry(ctx)
+	resp, err = b.failover(ctx, resp, err)
+	if resp != nil {
+		resp.Attempts = int(atomic.LoadInt32(&b.attempts))
+	}
+	return resp, err
+}
+
+// flightKey identifies identical calls by method, URL, headers and authentication
+func (b *FastBuilder) flightKey() string {
+	var headers []string
+	for _, h := range b.client.headers {
+		headers = append(headers, fmt.Sprintf("%s: %v", strings.ToLower(h.Tag), h.Value))
+	}
+	for _, h := range b.options.Headers {
+		headers = append(headers, fmt.Sprintf("%s: %v", strings.ToLower(h.Tag), h.Value))
+	}
+	sort.Strings(headers)
+
+	auth := b.auth()
+	var key strings.Builder
+	fmt.Fprintf(&key, "%s %s\n%s\n", b.method, b.client.resolveURL(b.url), b.host)
+	fmt.Fprintf(&key, "%s:%s:%s:%p:%v:%+v\n", auth.Username, auth.Password, auth.Token, auth.TokenSource, auth.Digest, auth.APIKey)
+	key.WriteString(strings.Join(headers, "\n"))
+	return key.String()
+}
+
+// share returns a copy of a shared response that the caller can modify
+func (r *Response) share() *Response {
+	if r == nil {
+		return nil
+	}
+	shared := *r
+	shared.Headers = r.Headers.Clone()
+	shared.Body = bytes.Clone(r.Body)
+	return &shared
+}
@@ -0,0 +1,63 @@
+package apifast_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestSingleflight(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		header       func(caller int) string // X-Tenant header of each caller
+		wantRequests int
+	}{
+		{"identical GETs are collapsed", "GET", func(int) string { return "a" }, 1},
+		{"different headers are not collapsed", "GET", func(i int) string { return []string{"a", "b"}[i%2] }, 2},
+		{"POSTs are not collapsed", "POST", func(int) string { return "a" }, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On(tt.method, "/config").Reply(200, "shared").Delay(100 * time.Millisecond)
+			client := apifast.NewClient().Transport(mock).Singleflight()
+
+			var wg sync.WaitGroup
+			bodies := make([][]byte, 4)
+			for i := range bodies {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp, err := client.Build().
+						Uri("https://api.example.com/config").
+						Headers([]apifast.Header{{Tag: "X-Tenant", Value: tt.header(i)}}).
+						Method(tt.method).
+						Do()
+					if err != nil {
+						t.Errorf("caller %d: Do() error = %v", i, err)
+						return
+					}
+					bodies[i] = resp.Body
+				}()
+			}
+			wg.Wait()
+			if t.Failed() {
+				return
+			}
+
+			if got := len(mock.Requests()); got != tt.wantRequests {
+				t.Errorf("sent %d requests, want %d", got, tt.wantRequests)
+			}
+			// Every caller owns its copy of the shared body
+			bodies[0][0] = 'X'
+			for i, body := range bodies[1:] {
+				if string(body) != "shared" {
+					t.Errorf("caller %d body = %q, want %q", i+1, body, "shared")
+				}
+			}
+		})
+	}
+}
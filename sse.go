@@ -0,0 +1,224 @@
+package apifast
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a server-sent event
+type Event struct {
+	ID    string // Last event ID seen on the stream
+	Event string // Event type, "message" if the server did not name it
+	Data  string // Event payload, multi-line data joined with newlines
+}
+
+// EventStream delivers the events of a server-sent events endpoint. Events is
+// closed when the stream ends; Err then reports why.
+type EventStream struct {
+	Events <-chan Event
+
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	err         error
+	lastEventID string
+	retry       time.Duration
+}
+
+// Err returns the error that ended the stream, nil if it was closed by Close
+func (s *EventStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close stops the stream and its reconnection loop
+func (s *EventStream) Close() {
+	s.cancel()
+}
+
+// SSE connects to a text/event-stream endpoint and delivers its events on the
+// returned stream. Dropped connections are re-established after the server's
+// retry delay (3s by default) with the Last-Event-ID header set. A 204 response
+// ends the stream, other non-2xx responses end it with a *StatusError.
+func (b *FastBuilder) SSE() *EventStream {
+	parent := b.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	events := make(chan Event)
+	internal := make(chan Event)
+	s := &EventStream{Events: events, cancel: cancel, retry: 3 * time.Second}
+
+	go s.read(ctx, b, internal)
+
+	// Forward events so Events closes as soon as the stream is canceled,
+	// even while the reader is blocked on the connection
+	go func() {
+		defer close(events)
+		for {
+			select {
+			case ev, ok := <-internal:
+				if !ok {
+					return
+				}
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					s.setErr(parent.Err())
+					return
+				}
+			case <-ctx.Done():
+				s.setErr(parent.Err())
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// read connects and reconnects to the endpoint until the stream ends
+func (s *EventStream) read(ctx context.Context, b *FastBuilder, out chan<- Event) {
+	defer close(out)
+	for {
+		conn := *b
+		conn.ctx = ctx
+		if conn.method == "" {
+			conn.method = "GET"
+		}
+		headers := append(slices.Clip(b.options.Headers),
+			Header{Tag: "Accept", Value: "text/event-stream"},
+			Header{Tag: "Cache-Control", Value: "no-cache"})
+		s.mu.Lock()
+		if s.lastEventID != "" {
+			headers = append(headers, Header{Tag: "Last-Event-ID", Value: s.lastEventID})
+		}
+		s.mu.Unlock()
+		conn.options.Headers = headers
+
+		parser := &sseParser{stream: s, emit: func(ev Event) error {
+			select {
+			case out <- ev:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}}
+		resp, err := conn.ResultWriter(parser).makeRequest()
+
+		var statusErr *StatusError
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.As(err, &statusErr):
+			s.setErr(err)
+			return
+		case err == nil && resp.Code == 204:
+			return
+		}
+
+		s.mu.Lock()
+		retry := s.retry
+		s.mu.Unlock()
+		if sleep(ctx, retry) != nil {
+			return
+		}
+	}
+}
+
+func (s *EventStream) setErr(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+}
+
+// sseParser is an io.Writer parsing the text/event-stream format
+type sseParser struct {
+	stream  *EventStream
+	emit    func(Event) error
+	buf     []byte
+	event   string
+	data    strings.Builder
+	hasData bool
+}
+
+func (p *sseParser) Write(chunk []byte) (int, error) {
+	p.buf = append(p.buf, chunk...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimSuffix(p.buf[:i], []byte("\r"))
+		if err := p.line(string(line)); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(chunk), nil
+}
+
+// line processes a single line of the stream
+func (p *sseParser) line(line string) error {
+	if line == "" {
+		return p.dispatch()
+	}
+	if strings.HasPrefix(line, ":") {
+		return nil
+	}
+
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimPrefix(value, " ")
+	switch field {
+	case "event":
+		p.event = value
+	case "data":
+		p.data.WriteString(value)
+		p.data.WriteByte('\n')
+		p.hasData = true
+	case "id":
+		if !strings.ContainsRune(value, 0) {
+			p.stream.mu.Lock()
+			p.stream.lastEventID = value
+			p.stream.mu.Unlock()
+		}
+	case "retry":
+		if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+			p.stream.mu.Lock()
+			p.stream.retry = time.Duration(ms) * time.Millisecond
+			p.stream.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// dispatch emits the buffered event, if it carries data
+func (p *sseParser) dispatch() error {
+	defer func() {
+		p.event = ""
+		p.data.Reset()
+		p.hasData = false
+	}()
+	if !p.hasData {
+		return nil
+	}
+
+	ev := Event{Event: p.event, Data: strings.TrimSuffix(p.data.String(), "\n")}
+	if ev.Event == "" {
+		ev.Event = "message"
+	}
+	p.stream.mu.Lock()
+	ev.ID = p.stream.lastEventID
+	p.stream.mu.Unlock()
+	return p.emit(ev)
+}
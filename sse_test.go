@@ -0,0 +1,108 @@
+package apifast_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+	"github.com/eantaru/apifast/apifasttest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestSSE(t *testing.T) {
+	srv := apifasttest.NewServer(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/event-stream")
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			w.WriteString(": comment\n\nid: 1\ndata: hello\n\nevent: update\ndata: a\ndata: b\n\n")
+			w.Flush()
+		})
+	})
+	defer srv.Close()
+
+	stream := srv.Client.Build().Uri("/events").SSE()
+	defer stream.Close()
+	var got []apifast.Event
+	for ev := range stream.Events {
+		if got = append(got, ev); len(got) == 2 {
+			break
+		}
+	}
+	want := []apifast.Event{
+		{ID: "1", Event: "message", Data: "hello"},
+		{ID: "1", Event: "update", Data: "a\nb"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
+
+func TestStreamCanceledBeforeHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream func(b *apifast.FastBuilder) error
+	}{
+		{
+			name: "download",
+			stream: func(b *apifast.FastBuilder) error {
+				_, err := b.Download(filepath.Join(t.TempDir(), "file.bin"))
+				return err
+			},
+		},
+		{
+			name: "result writer",
+			stream: func(b *apifast.FastBuilder) error {
+				_, err := b.ResultWriter(&bytes.Buffer{}).Get()
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The server never answers until the test is over
+			release := make(chan struct{})
+			srv := apifasttest.NewServer(func(ctx *fasthttp.RequestCtx) { <-release })
+			defer srv.Close()
+			defer close(release)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+			returned := make(chan error, 1)
+			go func() { returned <- tt.stream(srv.Client.Build().WithContext(ctx).Uri("/file.bin")) }()
+
+			select {
+			case err := <-returned:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("error = %v, want context.Canceled", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("stream still waiting for headers after its context was canceled")
+			}
+		})
+	}
+}
+
+func TestStreamWithContext(t *testing.T) {
+	content := testContent(256 << 10)
+	srv := apifasttest.NewHTTPServer(&fileServer{content: content, etag: `"v1"`})
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	for range 3 {
+		// The connection a stream was read from is reused by the next one
+		buf.Reset()
+		if _, err := srv.Client.Build().WithContext(ctx).Uri("/file.bin").ResultWriter(&buf).Get(); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), content) {
+			t.Fatalf("streamed %d bytes that differ from the %d byte file", buf.Len(), len(content))
+		}
+	}
+}
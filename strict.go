@@ -0,0 +1,69 @@
+package apifast
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrStrictDecoder reports StrictJSON or UseNumber on a request whose result
+// would not be decoded by encoding/json, which implements them
+var ErrStrictDecoder = errors.New("StrictJSON and UseNumber require the default encoding/json decoder")
+
+// StrictJSON decodes the Result with encoding/json, rejecting fields the
+// result type does not declare and data trailing the JSON document, so schema
+// drift in upstream APIs surfaces as an error instead of being ignored. It
+// fails with ErrStrictDecoder combined with a JSONCodec, a format such as
+// ResultXML, or a Decoder registered for the response content type.
+func (b *FastBuilder) StrictJSON() *FastBuilder {
+	b.strictJSON = true
+	return b
+}
+
+// UseNumber decodes numbers in interface{} values of the Result as json.Number
+// instead of float64, preserving large integers and exact decimals. Like
+// StrictJSON, it requires the default encoding/json decoder.
+func (b *FastBuilder) UseNumber() *FastBuilder {
+	b.useNumber = true
+	return b
+}
+
+// decodeResult maps a successful response body into the result
+func (b *FastBuilder) decodeResult(response *Response) error {
+	if !b.strictJSON && !b.useNumber {
+		return b.decode(response, b.result)
+	}
+	if err := b.checkStrictDecoder(); err != nil {
+		return err
+	}
+	if _, isJSON := b.client.lookupDecoder(response.Headers.Get("Content-Type")); !isJSON {
+		return ErrStrictDecoder
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(response.Body))
+	if b.strictJSON {
+		dec.DisallowUnknownFields()
+	}
+	if b.useNumber {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(b.result); err != nil {
+		return err
+	}
+	if b.strictJSON {
+		if _, err := dec.Token(); err != io.EOF {
+			return errors.New("json: unexpected data after top-level value")
+		}
+	}
+	return nil
+}
+
+// checkStrictDecoder fails if strict decoding is requested along with a
+// decoder that replaces encoding/json for every response
+func (b *FastBuilder) checkStrictDecoder() error {
+	if b.result != nil && (b.strictJSON || b.useNumber) && (b.decoder != nil || b.client.codec != nil) {
+		return ErrStrictDecoder
+	}
+	return nil
+}
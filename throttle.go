@@ -0,0 +1,80 @@
+package apifast
+
+import (
+	"net"
+	"time"
+)
+
+// RateLimitBytes caps the bandwidth of the client's connections at
+// bytesPerSec for uploads and, separately, for downloads, shared by all
+// connections of the client and its groups, so bulk transfers do not
+// saturate the network of a shared host. Connections through a proxy and
+// over HTTP/3 are not throttled.
+func (c *Client) RateLimitBytes(bytesPerSec int) *Client {
+	if bytesPerSec <= 0 {
+		c.bandwidth = nil
+		return c
+	}
+	// Transfers are metered in chunks that each take at most a tenth of a second
+	chunk := min(max(bytesPerSec/10, 1), 32*1024)
+	c.bandwidth = &bandwidth{
+		chunk: chunk,
+		read:  newTokenBucket(float64(bytesPerSec), chunk),
+		write: newTokenBucket(float64(bytesPerSec), chunk),
+	}
+	return c
+}
+
+// bandwidth holds the byte budgets of a client's connections
+type bandwidth struct {
+	chunk int
+	read  *tokenBucket
+	write *tokenBucket
+}
+
+// wrap throttles the reads and writes of conn
+func (bw *bandwidth) wrap(conn net.Conn) net.Conn {
+	return &throttledConn{Conn: conn, bw: bw}
+}
+
+// throttledConn is a connection whose transfers draw from a bandwidth budget
+type throttledConn struct {
+	net.Conn
+	bw *bandwidth
+}
+
+func (c *throttledConn) Read(p []byte) (int, error) {
+	if len(p) > c.bw.chunk {
+		p = p[:c.bw.chunk]
+	}
+	n, err := c.Conn.Read(p)
+	time.Sleep(c.bw.read.drain(n))
+	return n, err
+}
+
+func (c *throttledConn) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), c.bw.chunk)]
+		time.Sleep(c.bw.write.drain(len(chunk)))
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// drain draws n tokens, returning how long to wait until the budget covers them
+func (tb *tokenBucket) drain(n int) time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(time.Now())
+	tb.tokens -= float64(n)
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
@@ -0,0 +1,124 @@
+package apifast
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DialTimeout bounds how long establishing a TCP connection may take. A
+// shorter request deadline still takes precedence.
+func (c *Client) DialTimeout(timeout time.Duration) *Client {
+	c.dialTimeout = timeout
+	return c
+}
+
+// TLSHandshakeTimeout bounds how long the TLS handshake of a new connection may take
+func (c *Client) TLSHandshakeTimeout(timeout time.Duration) *Client {
+	c.tlsHandshakeTimeout = timeout
+	return c
+}
+
+// ReadTimeout bounds how long reading a response, headers and body, may take
+func (c *Client) ReadTimeout(timeout time.Duration) *Client {
+	c.hc.ReadTimeout = timeout
+	return c
+}
+
+// WriteTimeout bounds how long writing a request, headers and body, may take
+func (c *Client) WriteTimeout(timeout time.Duration) *Client {
+	c.hc.WriteTimeout = timeout
+	return c
+}
+
+// configureTimeouts wires the dialer and handshake timeout into the fasthttp client
+func (c *Client) configureTimeouts() {
+	if c.hc.Dial == nil {
+		c.hc.DialTimeout = c.dial
+	} else {
+		dial := c.hc.Dial
+		c.hc.Dial = func(addr string) (net.Conn, error) {
+			conn, err := dial(addr)
+			if err != nil {
+				return nil, err
+			}
+			return c.setup.conns.track(conn), nil
+		}
+	}
+
+	// Timed connections complete the handshake while dialing to measure it
+	if c.tlsHandshakeTimeout > 0 || (c.timings != nil && c.hc.Dial == nil) {
+		handshakeTimeout := c.tlsHandshakeTimeout
+		c.hc.ConfigureClient = func(hc *fasthttp.HostClient) error {
+			if hc.IsTLS {
+				hc.DialTimeout = handshakeDial(hc, handshakeTimeout)
+				hc.Dial = nil
+			}
+			return nil
+		}
+	}
+}
+
+// handshakeDial wraps the dialer of hc so that it returns connections on which
+// the TLS handshake already completed, within timeout if positive or else the
+// write timeout of hc
+func handshakeDial(hc *fasthttp.HostClient, timeout time.Duration) fasthttp.DialFuncWithTimeout {
+	dial, dialTimeout := hc.Dial, hc.DialTimeout
+	return func(addr string, dialBudget time.Duration) (net.Conn, error) {
+		var conn net.Conn
+		var err error
+		switch {
+		case dialTimeout != nil:
+			conn, err = dialTimeout(addr, dialBudget)
+		case dial != nil:
+			conn, err = dial(addr)
+		case hc.DialDualStack:
+			conn, err = fasthttp.DialDualStackTimeout(fasthttp.AddMissingPort(addr, true), dialBudget)
+		default:
+			conn, err = fasthttp.DialTimeout(fasthttp.AddMissingPort(addr, true), dialBudget)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		cfg := &tls.Config{}
+		if hc.TLSConfig != nil {
+			cfg = hc.TLSConfig.Clone()
+		}
+		if cfg.ServerName == "" {
+			host, _, splitErr := net.SplitHostPort(addr)
+			if splitErr != nil {
+				host = addr
+			}
+			cfg.ServerName = host
+		}
+
+		// Handshake below the timing of a timed connection
+		timed, _ := conn.(*timedConn)
+		raw := conn
+		if timed != nil {
+			raw = timed.Conn
+		}
+		tlsConn := tls.Client(raw, cfg)
+		if timeout > 0 {
+			_ = tlsConn.SetDeadline(time.Now().Add(timeout))
+		} else if hc.WriteTimeout > 0 {
+			_ = tlsConn.SetDeadline(time.Now().Add(hc.WriteTimeout))
+		}
+		start := time.Now()
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return nil, fasthttp.ErrTLSHandshakeTimeout
+			}
+			return nil, err
+		}
+		_ = tlsConn.SetDeadline(time.Time{})
+		if timed != nil {
+			return timed.secure(tlsConn, time.Since(start)), nil
+		}
+		return tlsConn, nil
+	}
+}
@@ -0,0 +1,151 @@
+package apifast
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Timing breaks down the duration of the attempt that produced a Response
+type Timing struct {
+	DNS          time.Duration // Resolving the host, zero with fixed or literal addresses
+	Connect      time.Duration // Establishing the TCP connection
+	TLSHandshake time.Duration // Negotiating TLS, zero for plain connections
+	TTFB         time.Duration // From the start of the attempt to the first response byte
+	Total        time.Duration // The whole exchange, including redirects and the body
+	Reused       bool          // Whether the attempt reused a pooled connection, which reports no DNS, connect or TLS time
+}
+
+// Timings records the Timing of every attempt on its Response, so latency
+// can be attributed to DNS, connecting, TLS, the server or the transfer. DNS,
+// connect and TLS durations are only reported by the attempt that opened the
+// connection. Requests through a proxy, a net/http based protocol or a custom
+// Transport only report the total; pipelined requests share connections, so
+// their TTFB is approximate.
+func (c *Client) Timings() *Client {
+	c.timings = &connTimings{}
+	return c
+}
+
+// connTimings tracks the timing of the client's open connections
+type connTimings struct {
+	conns sync.Map // local address -> *connTiming
+}
+
+// connTiming records how a connection was established and when the response
+// to its current request started arriving
+type connTiming struct {
+	dns, connect, tls time.Duration
+
+	mu        sync.Mutex
+	used      bool      // dial durations were reported by an attempt
+	awaiting  bool      // a request was written since the last response byte
+	firstByte time.Time // first byte of the latest response
+}
+
+// connTimingKey carries the connTiming of a connection being dialed
+type connTimingKey struct{}
+
+// recordDNS adds the time since start to the DNS duration of the connection
+// being dialed with ctx, if timed
+func recordDNS(ctx context.Context, start time.Time) {
+	if timing, ok := ctx.Value(connTimingKey{}).(*connTiming); ok {
+		timing.dns += time.Since(start)
+	}
+}
+
+// track wraps conn so that the responses it carries are timed. Only TCP
+// connections are told apart by their local address.
+func (t *connTimings) track(conn net.Conn, timing *connTiming) net.Conn {
+	if _, ok := conn.LocalAddr().(*net.TCPAddr); !ok {
+		return conn
+	}
+	key := conn.LocalAddr().String()
+	t.conns.Store(key, timing)
+	return &timedConn{Conn: conn, timing: timing, untrack: func() { t.conns.CompareAndDelete(key, timing) }}
+}
+
+// attempt returns the Timing of an attempt started at start that took
+// elapsed and received resp, or nil without Timings
+func (t *connTimings) attempt(resp *fasthttp.Response, start time.Time, elapsed time.Duration) *Timing {
+	if t == nil {
+		return nil
+	}
+	timing := &Timing{Total: elapsed}
+	addr := resp.LocalAddr()
+	if addr == nil {
+		return timing
+	}
+	v, ok := t.conns.Load(addr.String())
+	if !ok {
+		return timing
+	}
+	conn := v.(*connTiming)
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if conn.used {
+		timing.Reused = true
+	} else {
+		timing.DNS, timing.Connect, timing.TLSHandshake = conn.dns, conn.connect, conn.tls
+		conn.used = true
+	}
+	if conn.firstByte.After(start) {
+		timing.TTFB = conn.firstByte.Sub(start)
+	}
+	return timing
+}
+
+// timedConn notes when the response to each request written on it arrives
+type timedConn struct {
+	net.Conn
+	timing    *connTiming
+	untrack   func()
+	closeOnce sync.Once
+}
+
+func (c *timedConn) Write(p []byte) (int, error) {
+	c.timing.mu.Lock()
+	c.timing.awaiting = true
+	c.timing.mu.Unlock()
+	return c.Conn.Write(p)
+}
+
+func (c *timedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.timing.mu.Lock()
+		if c.timing.awaiting {
+			c.timing.awaiting = false
+			c.timing.firstByte = time.Now()
+		}
+		c.timing.mu.Unlock()
+	}
+	return n, err
+}
+
+func (c *timedConn) Close() error {
+	c.closeOnce.Do(c.untrack)
+	return c.Conn.Close()
+}
+
+// secure replaces the connection with tlsConn, which completed its handshake
+// in d, keeping the response timing above TLS
+func (c *timedConn) secure(tlsConn *tls.Conn, d time.Duration) net.Conn {
+	c.timing.tls = d
+	c.Conn = tlsConn
+	return timedTLSConn{c}
+}
+
+// timedTLSConn is a timedConn over TLS; fasthttp leaves connections with a
+// Handshake method as they are
+type timedTLSConn struct {
+	*timedConn
+}
+
+func (c timedTLSConn) Handshake() error {
+	return c.Conn.(*tls.Conn).Handshake()
+}
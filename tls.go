@@ -0,0 +1,39 @@
+package apifast
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// TLS replaces the TLS configuration used for https connections
+func (c *Client) TLS(cfg *tls.Config) *Client {
+	c.hc.TLSConfig = cfg
+	return c
+}
+
+// RootCAs sets the certificate authorities used to verify servers
+func (c *Client) RootCAs(pool *x509.CertPool) *Client {
+	c.tlsConfig().RootCAs = pool
+	return c
+}
+
+// ClientCert adds a client certificate presented for mutual TLS
+func (c *Client) ClientCert(cert tls.Certificate) *Client {
+	cfg := c.tlsConfig()
+	cfg.Certificates = append(cfg.Certificates, cert)
+	return c
+}
+
+// InsecureSkipVerify disables server certificate verification; use for testing only
+func (c *Client) InsecureSkipVerify() *Client {
+	c.tlsConfig().InsecureSkipVerify = true
+	return c
+}
+
+// tlsConfig returns the client's TLS configuration, creating it on first use
+func (c *Client) tlsConfig() *tls.Config {
+	if c.hc.TLSConfig == nil {
+		c.hc.TLSConfig = &tls.Config{}
+	}
+	return c.hc.TLSConfig
+}
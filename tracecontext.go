@@ -0,0 +1,90 @@
+package apifast
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceContextKey is the context key of forwarded trace headers
+type traceContextKey struct{}
+
+type traceContext struct {
+	parent string // traceparent header
+	state  string // tracestate header
+}
+
+// WithTraceContext returns a context carrying the W3C traceparent and
+// tracestate headers of an incoming request, so they are forwarded with the
+// requests made under it
+func WithTraceContext(ctx context.Context, traceparent, tracestate string) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext{parent: traceparent, state: tracestate})
+}
+
+// B3Propagation additionally sends the trace context in the single b3 header,
+// for services traced with Zipkin style propagation
+func (c *Client) B3Propagation() *Client {
+	c.b3 = true
+	return c
+}
+
+// NoTracePropagation stops the client from sending trace headers, for example
+// when it talks to third-party APIs
+func (c *Client) NoTracePropagation() *Client {
+	c.noTrace = true
+	return c
+}
+
+// propagateTrace adds the trace headers of ctx to req, unless tracing is
+// disabled or the caller set their own. An OpenTelemetry span in ctx takes
+// precedence over headers forwarded with WithTraceContext.
+func (b *FastBuilder) propagateTrace(ctx context.Context, req *fasthttp.Request) {
+	if b.client.noTrace || len(req.Header.Peek("traceparent")) > 0 {
+		return
+	}
+
+	var tc traceContext
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		tc = traceContext{
+			parent: "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String(),
+			state:  sc.TraceState().String(),
+		}
+	} else if forwarded, ok := ctx.Value(traceContextKey{}).(traceContext); ok && validTraceParent(forwarded.parent) {
+		tc = forwarded
+	} else {
+		return
+	}
+
+	req.Header.Set("traceparent", tc.parent)
+	if tc.state != "" {
+		req.Header.Set("tracestate", tc.state)
+	}
+	if b.client.b3 && len(req.Header.Peek("b3")) == 0 {
+		// version-traceid-spanid-flags becomes traceid-spanid-sampled
+		parts := strings.Split(tc.parent, "-")
+		sampled := "0"
+		if flags, _ := strconv.ParseUint(parts[3], 16, 8); flags&1 == 1 {
+			sampled = "1"
+		}
+		req.Header.Set("b3", parts[1]+"-"+parts[2]+"-"+sampled)
+	}
+}
+
+// validTraceParent reports whether s is a well-formed traceparent header with
+// non-zero trace and parent IDs
+func validTraceParent(s string) bool {
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return false
+	}
+	for _, part := range parts[:4] {
+		if strings.Trim(part, "0123456789abcdef") != "" {
+			return false
+		}
+	}
+	return strings.Trim(parts[1], "0") != "" && strings.Trim(parts[2], "0") != ""
+}
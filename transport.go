@@ -0,0 +1,51 @@
+package apifast
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Transport sends a fully built request and reads the response. It is
+// satisfied by *fasthttp.Client, *fasthttp.HostClient and
+// *fasthttp.PipelineClient, and lets tests substitute a MockTransport.
+type Transport interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+	DoDeadline(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error
+}
+
+// TransportFunc adapts a plain function to the Transport interface. The
+// deadline is zero for requests without one.
+type TransportFunc func(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error
+
+func (f TransportFunc) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	return f(req, resp, time.Time{})
+}
+
+func (f TransportFunc) DoDeadline(req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
+	return f(req, resp, deadline)
+}
+
+// HTTPTransport bridges a net/http RoundTripper to the Transport interface,
+// so requests can be sent through any net/http based client stack, such as
+// an instrumented http.Transport or a protocol bridge
+func HTTPTransport(rt http.RoundTripper) Transport {
+	return newStdTransport(rt, 0)
+}
+
+// Transport sends the client's requests through t instead of its own
+// fasthttp.Client. Client settings that configure the fasthttp.Client, such as
+// TLS, proxies and MaxResponseBodySize, do not apply to a custom transport.
+func (c *Client) Transport(t Transport) *Client {
+	c.transport = t
+	return c
+}
+
+// rt returns the transport requests are sent through
+func (c *Client) rt() Transport {
+	if c.transport != nil {
+		return c.transport
+	}
+	return c.pool
+}
@@ -0,0 +1,223 @@
+package apifast
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UploadProtocol speaks a chunked upload protocol such as S3 multipart
+// uploads. Its methods send their requests through the given client.
+type UploadProtocol interface {
+	// Start begins an upload and returns its ID
+	Start(ctx context.Context, c *Client) (uploadID string, err error)
+	// UploadPart sends part number part, counted from 1, and returns the
+	// token that identifies it when the upload completes, such as its ETag
+	UploadPart(ctx context.Context, c *Client, uploadID string, part int, data []byte) (token string, err error)
+	// Complete assembles the parts, given their tokens in order
+	Complete(ctx context.Context, c *Client, uploadID string, tokens []string) error
+	// Abort discards an upload that failed
+	Abort(ctx context.Context, c *Client, uploadID string) error
+}
+
+// ChunkedUpload controls how UploadParts splits and sends a large upload
+type ChunkedUpload struct {
+	Protocol    UploadProtocol              // Upload protocol, such as S3Multipart
+	PartSize    int64                       // Bytes per part, 8 MiB if zero
+	Concurrency int                         // Parts uploaded in parallel, 1 if zero
+	PartRetries int                         // Retries of a failed part, 3 if zero
+	OnProgress  func(uploaded, total int64) // Called after every part, may be called concurrently
+}
+
+// UploadParts splits the size bytes of r into parts and uploads them with
+// the protocol of u, Concurrency parts at a time, then completes the
+// upload. Failed parts are retried after the default backoff; once one fails
+// for good the upload is aborted and the error returned.
+func (c *Client) UploadParts(ctx context.Context, r io.ReaderAt, size int64, u ChunkedUpload) error {
+	partSize := u.PartSize
+	if partSize <= 0 {
+		partSize = 8 << 20
+	}
+	concurrency := max(u.Concurrency, 1)
+	retries := u.PartRetries
+	if retries <= 0 {
+		retries = 3
+	}
+	parts := int(max((size+partSize-1)/partSize, 1))
+
+	uploadID, err := u.Protocol.Start(ctx, c)
+	if err != nil {
+		return fmt.Errorf("failed to start upload: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		failed   error
+		uploaded int64
+		tokens   = make([]string, parts)
+	)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, parts) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, partSize)
+			for part := range jobs {
+				offset := int64(part) * partSize
+				data := buf[:min(partSize, size-offset)]
+				token, err := c.uploadPart(ctx, u, uploadID, r, offset, part+1, data, retries)
+
+				mu.Lock()
+				if err != nil && failed == nil {
+					failed = fmt.Errorf("failed to upload part %d: %w", part+1, err)
+					cancel()
+				}
+				tokens[part] = token
+				uploaded += int64(len(data))
+				progress := uploaded
+				mu.Unlock()
+				if err == nil && u.OnProgress != nil {
+					u.OnProgress(progress, size)
+				}
+			}
+		}()
+	}
+
+feed:
+	for part := range parts {
+		select {
+		case jobs <- part:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed == nil && ctx.Err() != nil {
+		failed = ctx.Err()
+	}
+	if failed == nil {
+		if err := u.Protocol.Complete(ctx, c, uploadID, tokens); err != nil {
+			failed = fmt.Errorf("failed to complete upload: %w", err)
+		}
+	}
+	if failed != nil {
+		// Abort even when the caller gave up, so no parts are left behind
+		abortCtx, cancelAbort := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancelAbort()
+		if err := u.Protocol.Abort(abortCtx, c, uploadID); err != nil {
+			return errors.Join(failed, fmt.Errorf("failed to abort upload: %w", err))
+		}
+		return failed
+	}
+	return nil
+}
+
+// uploadPart reads part number part from r at offset into data and uploads
+// it, retrying up to retries times
+func (c *Client) uploadPart(ctx context.Context, u ChunkedUpload, uploadID string, r io.ReaderAt, offset int64, part int, data []byte, retries int) (string, error) {
+	if _, err := r.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	for attempt := 1; ; attempt++ {
+		token, err := u.Protocol.UploadPart(ctx, c, uploadID, part, data)
+		if err == nil || attempt > retries || ctx.Err() != nil {
+			return token, err
+		}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.Code != 429 && statusErr.Code < 500 {
+			return "", err
+		}
+		if err := sleep(ctx, DefaultBackoff.Delay(attempt)); err != nil {
+			return "", err
+		}
+	}
+}
+
+// S3Multipart returns the S3 multipart upload protocol for the object key,
+// relative to the client's base URL, which addresses the bucket. Requests go
+// through the client, so S3 authentication is left to its request hooks or
+// to presigned query parameters in the base URL.
+func S3Multipart(key string) UploadProtocol {
+	return s3Multipart{key: key}
+}
+
+// s3Multipart implements the S3 CreateMultipartUpload, UploadPart,
+// CompleteMultipartUpload and AbortMultipartUpload calls
+type s3Multipart struct {
+	key string
+}
+
+func (s s3Multipart) Start(ctx context.Context, c *Client) (string, error) {
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	_, err := c.Build().WithContext(ctx).Uri(s.key + "?uploads").ResultXML(&result).Post()
+	if err != nil {
+		return "", err
+	}
+	if result.UploadID == "" {
+		return "", errors.New("missing UploadId in response")
+	}
+	return result.UploadID, nil
+}
+
+func (s s3Multipart) UploadPart(ctx context.Context, c *Client, uploadID string, part int, data []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(part)}, "uploadId": {uploadID}}
+	resp, err := c.Build().WithContext(ctx).Uri(s.key + "?" + query.Encode()).Payload(data).Put()
+	if err != nil {
+		return "", err
+	}
+	etag := resp.Headers.Get("ETag")
+	if etag == "" {
+		return "", errors.New("missing ETag in response")
+	}
+	return etag, nil
+}
+
+func (s s3Multipart) Complete(ctx context.Context, c *Client, uploadID string, tokens []string) error {
+	type completedPart struct {
+		PartNumber int
+		ETag       string
+	}
+	request := struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{}
+	for i, etag := range tokens {
+		request.Parts = append(request.Parts, completedPart{PartNumber: i + 1, ETag: etag})
+	}
+
+	// S3 may report a failure in the body of a 200 response
+	var result struct {
+		XMLName xml.Name
+		Code    string
+		Message string
+	}
+	query := url.Values{"uploadId": {uploadID}}
+	_, err := c.Build().WithContext(ctx).Uri(s.key + "?" + query.Encode()).PayloadXML(request).ResultXML(&result).Post()
+	if err != nil {
+		return err
+	}
+	if result.XMLName.Local == "Error" {
+		return fmt.Errorf("%s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+func (s s3Multipart) Abort(ctx context.Context, c *Client, uploadID string) error {
+	query := url.Values{"uploadId": {uploadID}}
+	_, err := c.Build().WithContext(ctx).Uri(s.key + "?" + query.Encode()).Delete()
+	return err
+}
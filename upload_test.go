@@ -0,0 +1,211 @@
+package apifast_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/eantaru/apifast"
+	"github.com/eantaru/apifast/apifasttest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestUploadParts(t *testing.T) {
+	content := testContent(10)
+	tests := []struct {
+		name         string
+		size         int64
+		retries      int
+		failures     map[int][]int // statuses answered to the first attempts of a part
+		wantErr      bool
+		wantAttempts map[int]int // attempts per part, 1 if not listed
+	}{
+		{name: "parts are assembled in order", size: 10},
+		{name: "empty upload sends one part", size: 0},
+		{name: "5xx part is retried", size: 10, failures: map[int][]int{2: {503}}, wantAttempts: map[int]int{2: 2}},
+		{name: "429 part is retried", size: 10, failures: map[int][]int{3: {429}}, wantAttempts: map[int]int{3: 2}},
+		{name: "4xx part aborts the upload", size: 10, failures: map[int][]int{2: {400}}, wantErr: true},
+		{name: "retries are capped", size: 10, retries: 1, failures: map[int][]int{1: {503, 503}}, wantErr: true, wantAttempts: map[int]int{1: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol := &memoryUpload{failures: tt.failures, parts: map[int][]byte{}, attempts: map[int]int{}}
+			var mu sync.Mutex
+			var progress []int64
+			err := apifast.NewClient().UploadParts(context.Background(), bytes.NewReader(content), tt.size, apifast.ChunkedUpload{
+				Protocol:    protocol,
+				PartSize:    4,
+				Concurrency: 2,
+				PartRetries: tt.retries,
+				OnProgress: func(uploaded, total int64) {
+					mu.Lock()
+					progress = append(progress, uploaded)
+					mu.Unlock()
+				},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadParts() error = %v, want error %t", err, tt.wantErr)
+			}
+			if protocol.aborted != tt.wantErr {
+				t.Errorf("aborted = %t, want %t", protocol.aborted, tt.wantErr)
+			}
+			for part, n := range protocol.attempts {
+				want := tt.wantAttempts[part]
+				if want == 0 {
+					want = 1
+				}
+				if n != want {
+					t.Errorf("part %d sent %d times, want %d", part, n, want)
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var assembled []byte
+			for _, token := range protocol.completed {
+				part, _ := strconv.Atoi(token)
+				assembled = append(assembled, protocol.parts[part]...)
+			}
+			if !bytes.Equal(assembled, content[:tt.size]) {
+				t.Errorf("assembled %q, want %q", assembled, content[:tt.size])
+			}
+			if slices.Sort(progress); len(progress) == 0 || progress[len(progress)-1] != tt.size {
+				t.Errorf("progress = %v, want it to end at %d", progress, tt.size)
+			}
+		})
+	}
+}
+
+// memoryUpload is an UploadProtocol keeping the parts in memory, whose tokens
+// are the part numbers
+type memoryUpload struct {
+	mu        sync.Mutex
+	failures  map[int][]int
+	attempts  map[int]int
+	parts     map[int][]byte
+	completed []string
+	aborted   bool
+}
+
+func (m *memoryUpload) Start(context.Context, *apifast.Client) (string, error) { return "u1", nil }
+
+func (m *memoryUpload) UploadPart(_ context.Context, _ *apifast.Client, _ string, part int, data []byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts[part]++
+	if failures := m.failures[part]; m.attempts[part] <= len(failures) {
+		return "", &apifast.StatusError{Code: failures[m.attempts[part]-1]}
+	}
+	m.parts[part] = bytes.Clone(data)
+	return strconv.Itoa(part), nil
+}
+
+func (m *memoryUpload) Complete(_ context.Context, _ *apifast.Client, _ string, tokens []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.completed = tokens
+	return nil
+}
+
+func (m *memoryUpload) Abort(context.Context, *apifast.Client, string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.aborted = true
+	return nil
+}
+
+func TestS3Multipart(t *testing.T) {
+	tests := []struct {
+		name        string
+		completeErr bool // CompleteMultipartUpload reports an error in a 200 response
+		wantErr     bool
+	}{
+		{name: "upload completes"},
+		{name: "error in a 200 response aborts", completeErr: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s3 := &s3Server{completeErr: tt.completeErr, parts: map[string][]byte{}}
+			srv := apifasttest.NewServer(s3.handle)
+			defer srv.Close()
+
+			content := testContent(10)
+			err := srv.Client.UploadParts(context.Background(), bytes.NewReader(content), 10, apifast.ChunkedUpload{
+				Protocol: apifast.S3Multipart("/bucket/backup.tar"),
+				PartSize: 4,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadParts() error = %v, want error %t", err, tt.wantErr)
+			}
+			if s3.aborted != tt.wantErr {
+				t.Errorf("aborted = %t, want %t", s3.aborted, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(s3.object, content) {
+				t.Errorf("object = %q, want %q", s3.object, content)
+			}
+		})
+	}
+}
+
+// s3Server answers the S3 multipart upload calls for a single upload
+type s3Server struct {
+	completeErr bool
+
+	mu      sync.Mutex
+	parts   map[string][]byte // by ETag
+	object  []byte
+	aborted bool
+}
+
+func (s *s3Server) handle(ctx *fasthttp.RequestCtx) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	args := ctx.QueryArgs()
+	if string(ctx.Path()) != "/bucket/backup.tar" {
+		ctx.SetStatusCode(404)
+		return
+	}
+	switch {
+	case ctx.IsPost() && args.Has("uploads"):
+		ctx.SetBodyString(`<InitiateMultipartUploadResult><UploadId>u1</UploadId></InitiateMultipartUploadResult>`)
+	case string(args.Peek("uploadId")) != "u1":
+		ctx.SetStatusCode(404)
+	case ctx.IsPut():
+		etag := `"etag-` + string(args.Peek("partNumber")) + `"`
+		s.parts[etag] = bytes.Clone(ctx.PostBody())
+		ctx.Response.Header.Set("ETag", etag)
+	case ctx.IsPost():
+		var request struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(ctx.PostBody(), &request); err != nil {
+			ctx.SetStatusCode(400)
+			return
+		}
+		if s.completeErr {
+			ctx.SetBodyString(`<Error><Code>InternalError</Code><Message>try again</Message></Error>`)
+			return
+		}
+		for i, p := range request.Parts {
+			if p.PartNumber != i+1 {
+				ctx.SetStatusCode(400)
+				return
+			}
+			s.object = append(s.object, s.parts[p.ETag]...)
+		}
+		ctx.SetBodyString(`<CompleteMultipartUploadResult/>`)
+	case ctx.IsDelete():
+		s.aborted = true
+		ctx.SetStatusCode(204)
+	default:
+		ctx.Error("unexpected call", 400)
+	}
+}
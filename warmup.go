@@ -0,0 +1,43 @@
+package apifast
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Warmup opens up to n keep-alive connections to the host of the base URL,
+// completing their TLS handshakes, so the first requests do not pay for cold
+// connections. Each connection is opened by a HEAD request to the base URL
+// whose status is ignored; the connections then stay in the pool until they
+// exceed MaxIdleConnDuration.
+func (c *Client) Warmup(ctx context.Context, n int) error {
+	if c.baseURL == "" {
+		return errors.New("warmup requires a base URL")
+	}
+	if err := c.init(); err != nil {
+		return err
+	}
+
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := fasthttp.AcquireRequest()
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseRequest(req)
+			defer fasthttp.ReleaseResponse(resp)
+
+			req.SetRequestURI(c.baseURL)
+			req.Header.SetMethod(fasthttp.MethodHead)
+			resp.SkipBody = true
+			errs[i] = c.do(ctx, req, resp)
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,166 @@
+package apifast
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"time"
+)
+
+// WebhookSender delivers signed webhooks. Every attempt carries a fresh
+// timestamp and an HMAC over "timestamp.payload", so receivers can verify the
+// sender and reject replays; the delivery ID stays the same across attempts.
+type WebhookSender struct {
+	Secret          []byte           // Shared signing secret
+	Hash            func() hash.Hash // Hash algorithm, SHA-256 if nil
+	SignatureHeader string           // Header receiving the signature, "X-Webhook-Signature" if empty
+	TimestampHeader string           // Header receiving the unix timestamp, "X-Webhook-Timestamp" if empty
+	IDHeader        string           // Header receiving the delivery ID, "X-Webhook-ID" if empty
+	Base64          bool             // Encode the signature as base64 instead of hex
+	Prefix          string           // Prepended to the encoded signature, e.g. "sha256="
+	MaxAttempts     int              // Delivery attempts before giving up, 5 if zero
+	Backoff         Backoff          // Delay schedule between attempts, 1s doubling up to 5m if nil
+	Timeout         time.Duration    // Timeout of each attempt, 10s if zero
+	Client          *Client          // Client used to deliver, the default client if nil
+}
+
+// Delivery reports the outcome of a webhook delivery
+type Delivery struct {
+	ID        string // Delivery ID sent with every attempt
+	URL       string // Endpoint the webhook was sent to
+	Attempts  int    // Number of attempts made
+	Status    int    // Status of the last response, 0 if none arrived
+	Delivered bool   // Whether an attempt got a 2xx response
+	Err       error  // Error of the last attempt, nil once delivered
+}
+
+// webhookBackoff is used when a WebhookSender does not specify a backoff
+var webhookBackoff = ExponentialBackoff{
+	Initial:    time.Second,
+	Max:        5 * time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// Send posts payload as JSON to url, retrying network errors, 1xx, 3xx, 408,
+// 429 and 5xx responses with backoff, honouring Retry-After, until an attempt
+// gets a 2xx response. Other statuses fail the delivery at once. The returned
+// delivery is never nil; the error is its Err.
+func (w *WebhookSender) Send(ctx context.Context, url string, payload []byte) (*Delivery, error) {
+	maxAttempts := w.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	backoff := w.Backoff
+	if backoff == nil {
+		backoff = webhookBackoff
+	}
+
+	d := &Delivery{ID: newUUID(), URL: url}
+	for {
+		d.Attempts++
+		resp, err := w.attempt(ctx, d, payload)
+		d.Status = 0
+		if resp != nil {
+			d.Status = resp.Code
+		}
+		if err == nil && (d.Status < 200 || d.Status >= 300) {
+			// Informational and redirect responses do not deliver the webhook
+			err = &StatusError{Code: resp.Code, Body: resp.Body}
+		}
+		d.Err = err
+		if err == nil {
+			d.Delivered = true
+			return d, nil
+		}
+		if d.Attempts >= maxAttempts || !webhookRetryable(ctx, err) {
+			return d, err
+		}
+
+		delay := backoff.Delay(d.Attempts)
+		if wait, ok := retryAfter(resp); ok {
+			delay = wait
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return d, d.Err
+		}
+	}
+}
+
+// SendJSON marshals v and delivers it like Send
+func (w *WebhookSender) SendJSON(ctx context.Context, url string, v interface{}) (*Delivery, error) {
+	payload, err := w.client().json().Marshal(v)
+	if err != nil {
+		err = fmt.Errorf("failed to encode JSON payload: %w", err)
+		return &Delivery{URL: url, Err: err}, err
+	}
+	return w.Send(ctx, url, payload)
+}
+
+// attempt sends one signed delivery attempt
+func (w *WebhookSender) attempt(ctx context.Context, d *Delivery, payload []byte) (*Response, error) {
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	headers := []Header{
+		{Tag: "Content-Type", Value: "application/json"},
+		{Tag: w.header(w.IDHeader, "X-Webhook-ID"), Value: d.ID},
+		{Tag: w.header(w.TimestampHeader, "X-Webhook-Timestamp"), Value: timestamp},
+		{Tag: w.header(w.SignatureHeader, "X-Webhook-Signature"), Value: w.Prefix + w.sign(timestamp, payload)},
+	}
+	return w.client().Build().WithContext(ctx).Uri(d.URL).Headers(headers).Payload(payload).Timeout(timeout).Post()
+}
+
+// sign computes the encoded HMAC of timestamp and payload
+func (w *WebhookSender) sign(timestamp string, payload []byte) string {
+	newHash := w.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	mac := hmac.New(newHash, w.Secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	sum := mac.Sum(nil)
+	if w.Base64 {
+		return base64.StdEncoding.EncodeToString(sum)
+	}
+	return hex.EncodeToString(sum)
+}
+
+// header returns name, or fallback if it is empty
+func (w *WebhookSender) header(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// client returns the client delivering webhooks
+func (w *WebhookSender) client() *Client {
+	if w.Client == nil {
+		return defaultClient
+	}
+	return w.Client
+}
+
+// webhookRetryable reports whether a failed delivery attempt is worth repeating
+func webhookRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code < 400 || statusErr.Code == 408 || statusErr.Code == 429 || statusErr.Code >= 500
+	}
+	var connErr *ConnError
+	return errors.Is(err, ErrTimeout) || errors.As(err, &connErr)
+}
@@ -0,0 +1,84 @@
+package apifast_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/eantaru/apifast"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int // status of the first attempt, later ones get 200
+		wantDelivered bool
+		wantAttempts  int
+	}{
+		{"2xx delivers at once", 204, true, 1},
+		{"1xx is retried", 102, true, 2},
+		{"3xx is retried", 302, true, 2},
+		{"408 is retried", 408, true, 2},
+		{"5xx is retried", 503, true, 2},
+		{"other 4xx fails at once", 400, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := apifast.NewMockTransport()
+			mock.On("POST", "/hook").Reply(tt.status, "").Times(1)
+			mock.On("POST", "/hook").Reply(200, "")
+			sender := &apifast.WebhookSender{
+				Secret:      []byte("secret"),
+				MaxAttempts: 3,
+				Backoff:     apifast.ConstantBackoff(time.Millisecond),
+				Client:      apifast.NewClient().Transport(mock),
+			}
+
+			d, err := sender.Send(context.Background(), "https://hooks.example.com/hook", []byte(`{"event":"paid"}`))
+			if d.Delivered != tt.wantDelivered || (err == nil) != tt.wantDelivered {
+				t.Fatalf("Delivered = %t, error = %v, want delivered %t", d.Delivered, err, tt.wantDelivered)
+			}
+			if d.Attempts != tt.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", d.Attempts, tt.wantAttempts)
+			}
+			if !tt.wantDelivered && d.Status != tt.status {
+				t.Errorf("Status = %d, want %d", d.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	mock := apifast.NewMockTransport()
+	mock.On("POST", "/hook").Reply(503, "").Times(1)
+	mock.On("POST", "/hook").Reply(200, "")
+	sender := &apifast.WebhookSender{
+		Secret:  []byte("secret"),
+		Prefix:  "sha256=",
+		Backoff: apifast.ConstantBackoff(time.Millisecond),
+		Client:  apifast.NewClient().Transport(mock),
+	}
+	payload := []byte(`{"event":"paid"}`)
+	if _, err := sender.Send(context.Background(), "https://hooks.example.com/hook", payload); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	requests := mock.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("sent %d attempts, want 2", len(requests))
+	}
+	for i, r := range requests {
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(r.Header.Get("X-Webhook-Timestamp") + "."))
+		mac.Write(payload)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Webhook-Signature") != want {
+			t.Errorf("attempt %d signed %q, want %q", i+1, r.Header.Get("X-Webhook-Signature"), want)
+		}
+	}
+	if first, second := requests[0].Header.Get("X-Webhook-ID"), requests[1].Header.Get("X-Webhook-ID"); first == "" || first != second {
+		t.Errorf("delivery IDs %q and %q, want the same ID on every attempt", first, second)
+	}
+}
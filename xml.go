@@ -0,0 +1,28 @@
+package apifast
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// PayloadXML encodes v as an application/xml payload, prefixed with the
+// standard XML declaration
+func (b *FastBuilder) PayloadXML(v interface{}) *FastBuilder {
+	payload, err := xml.Marshal(v)
+	if err != nil {
+		b.err = fmt.Errorf("failed to encode XML payload: %w", err)
+		return b
+	}
+	b.options.payload = append([]byte(xml.Header), payload...)
+	b.options.contentType = "application/xml"
+	return b
+}
+
+// ResultXML sets the destination for the XML decoded response body, whatever
+// content type the server reports. XML responses are also decoded into a plain
+// Result when their content type is XML.
+func (b *FastBuilder) ResultXML(result interface{}) *FastBuilder {
+	b.result = result
+	b.decoder = xml.Unmarshal
+	return b
+}